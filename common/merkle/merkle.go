@@ -0,0 +1,103 @@
+// Package merkle implements a binary Merkle tree over a list of items with
+// inclusion proofs.
+//
+// Leaves are hashed as keccak256(0x00 || item) and inner nodes as
+// keccak256(0x01 || left || right), so leaves can't be passed off as nodes.
+// The last node of a level with an odd number of nodes is carried up to the
+// next level unhashed. The root of an empty list is the zero hash.
+package merkle
+
+import (
+	"errors"
+
+	"execution/common"
+	"execution/crypto"
+)
+
+var (
+	leafPrefix = []byte{0x00}
+	nodePrefix = []byte{0x01}
+)
+
+// ErrIndexOutOfRange is returned when proving an item not in the list.
+var ErrIndexOutOfRange = errors.New("merkle: index out of range")
+
+// LeafHash returns the hash of an item as a leaf of the tree.
+func LeafHash(item []byte) common.Hash {
+	return crypto.Keccak256Hash(leafPrefix, item)
+}
+
+func nodeHash(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash(nodePrefix, left[:], right[:])
+}
+
+// Root returns the root of the tree over the given items.
+func Root(items [][]byte) common.Hash {
+	if len(items) == 0 {
+		return common.Hash{}
+	}
+	level := make([]common.Hash, len(items))
+	for i, item := range items {
+		level[i] = LeafHash(item)
+	}
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+// Prove returns the sibling hashes on the path from the item at index to the
+// root, bottom up. Levels where the path node is carried up have no sibling.
+func Prove(items [][]byte, index int) ([]common.Hash, error) {
+	if index < 0 || index >= len(items) {
+		return nil, ErrIndexOutOfRange
+	}
+	level := make([]common.Hash, len(items))
+	for i, item := range items {
+		level[i] = LeafHash(item)
+	}
+	var proof []common.Hash
+	for len(level) > 1 {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		level, index = nextLevel(level), index/2
+	}
+	return proof, nil
+}
+
+// Verify reports whether proof proves the item at index of a list of count
+// items to be included under root.
+func Verify(root common.Hash, item []byte, index, count int, proof []common.Hash) bool {
+	if index < 0 || index >= count {
+		return false
+	}
+	hash := LeafHash(item)
+	for ; count > 1; index, count = index/2, (count+1)/2 {
+		if index^1 >= count {
+			continue // Carried up without a sibling
+		}
+		if len(proof) == 0 {
+			return false
+		}
+		if index%2 == 0 {
+			hash = nodeHash(hash, proof[0])
+		} else {
+			hash = nodeHash(proof[0], hash)
+		}
+		proof = proof[1:]
+	}
+	return len(proof) == 0 && hash == root
+}
+
+// nextLevel hashes pairs of nodes into the level above.
+func nextLevel(level []common.Hash) []common.Hash {
+	next := make([]common.Hash, 0, (len(level)+1)/2)
+	for i := 0; i+1 < len(level); i += 2 {
+		next = append(next, nodeHash(level[i], level[i+1]))
+	}
+	if len(level)%2 == 1 {
+		next = append(next, level[len(level)-1])
+	}
+	return next
+}
@@ -0,0 +1,44 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"execution/common"
+)
+
+// Tests that every item of lists of various sizes can be proven against the
+// root, and that proofs don't verify other items, positions or roots.
+func TestProveVerify(t *testing.T) {
+	if root := Root(nil); root != (common.Hash{}) {
+		t.Fatalf("empty root mismatch: have %x, want zero", root)
+	}
+	for count := 1; count <= 17; count++ {
+		items := make([][]byte, count)
+		for i := range items {
+			items[i] = []byte(fmt.Sprintf("item %d", i))
+		}
+		root := Root(items)
+		for i := range items {
+			proof, err := Prove(items, i)
+			if err != nil {
+				t.Fatalf("count %d, item %d: failed to prove: %v", count, i, err)
+			}
+			if !Verify(root, items[i], i, count, proof) {
+				t.Errorf("count %d, item %d: proof failed to verify", count, i)
+			}
+			if Verify(root, []byte("other"), i, count, proof) {
+				t.Errorf("count %d, item %d: proof verified other item", count, i)
+			}
+			if count > 1 && Verify(root, items[i], (i+1)%count, count, proof) {
+				t.Errorf("count %d, item %d: proof verified at other index", count, i)
+			}
+			if Verify(common.Hash{0x01}, items[i], i, count, proof) {
+				t.Errorf("count %d, item %d: proof verified other root", count, i)
+			}
+		}
+		if _, err := Prove(items, count); err != ErrIndexOutOfRange {
+			t.Errorf("count %d: out of range error mismatch: have %v, want %v", count, err, ErrIndexOutOfRange)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"execution/common"
+	"execution/ethdb"
+	"execution/log"
+)
+
+// ReadCoin retrieves the encoded coin created by the given transaction output.
+func ReadCoin(db ethdb.KeyValueReader, txHash common.Hash, index uint32) []byte {
+	data, _ := db.Get(coinKey(txHash, index))
+	return data
+}
+
+// WriteCoin stores an encoded coin.
+func WriteCoin(db ethdb.KeyValueWriter, txHash common.Hash, index uint32, data []byte) {
+	if err := db.Put(coinKey(txHash, index), data); err != nil {
+		log.Crit("Failed to store coin", "err", err)
+	}
+}
+
+// WriteBlockCoin indexes a coin under the block which created it.
+func WriteBlockCoin(db ethdb.KeyValueWriter, number uint64, txHash common.Hash, index uint32) {
+	if err := db.Put(blockCoinKey(number, txHash, index), nil); err != nil {
+		log.Crit("Failed to store block coin index", "err", err)
+	}
+}
+
+// IterateBlockCoins calls f on the outputs of the coins created by a block, in
+// order of transaction hash and index, until f returns false.
+func IterateBlockCoins(db ethdb.Iteratee, number uint64, f func(txHash common.Hash, index uint32) bool) error {
+	prefix := append(blockCoinPrefix, encodeBlockNumber(number)...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+common.HashLength+4 {
+			continue
+		}
+		key = key[len(prefix):]
+		if !f(common.BytesToHash(key[:common.HashLength]), binary.BigEndian.Uint32(key[common.HashLength:])) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// ReadCoinRoot retrieves the root of the coins created by a block.
+func ReadCoinRoot(db ethdb.KeyValueReader, number uint64) common.Hash {
+	data, _ := db.Get(coinRootKey(number))
+	return common.BytesToHash(data)
+}
+
+// WriteCoinRoot stores the root of the coins created by a block.
+func WriteCoinRoot(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Put(coinRootKey(number), root.Bytes()); err != nil {
+		log.Crit("Failed to store coin root", "err", err)
+	}
+}
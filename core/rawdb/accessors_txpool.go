@@ -0,0 +1,45 @@
+package rawdb
+
+import (
+	"execution/common"
+	"execution/ethdb"
+	"execution/log"
+)
+
+// ReadPooledTransaction retrieves the encoded pool transaction with the given hash.
+func ReadPooledTransaction(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(pooledTxKey(hash))
+	return data
+}
+
+// WritePooledTransaction stores an encoded pool transaction.
+func WritePooledTransaction(db ethdb.KeyValueWriter, hash common.Hash, data []byte) {
+	if err := db.Put(pooledTxKey(hash), data); err != nil {
+		log.Crit("Failed to store pooled transaction", "err", err)
+	}
+}
+
+// DeletePooledTransaction removes an encoded pool transaction.
+func DeletePooledTransaction(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(pooledTxKey(hash)); err != nil {
+		log.Crit("Failed to delete pooled transaction", "err", err)
+	}
+}
+
+// IteratePooledTransactions calls f on every stored pool transaction until f
+// returns false.
+func IteratePooledTransactions(db ethdb.Iteratee, f func(hash common.Hash, data []byte) bool) error {
+	it := db.NewIterator(pooledTxPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(pooledTxPrefix)+common.HashLength {
+			continue
+		}
+		if !f(common.BytesToHash(key[len(pooledTxPrefix):]), it.Value()) {
+			break
+		}
+	}
+	return it.Error()
+}
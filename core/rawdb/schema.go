@@ -95,6 +95,10 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	pooledTxPrefix        = []byte("p") // pooledTxPrefix + hash -> pooled transaction
+	coinPrefix            = []byte("U") // coinPrefix + tx hash + index (uint32 big endian) -> coin
+	blockCoinPrefix       = []byte("u") // blockCoinPrefix + num (uint64 big endian) + tx hash + index (uint32 big endian) -> coins created by a block
+	coinRootPrefix        = []byte("R") // coinRootPrefix + num (uint64 big endian) -> root of the coins created by a block
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
@@ -141,6 +145,27 @@ func encodeBlockNumber(number uint64) []byte {
 	return enc
 }
 
+// pooledTxKey = pooledTxPrefix + hash
+func pooledTxKey(hash common.Hash) []byte {
+	return append(pooledTxPrefix, hash.Bytes()...)
+}
+
+// coinKey = coinPrefix + tx hash + index (uint32 big endian)
+func coinKey(txHash common.Hash, index uint32) []byte {
+	return binary.BigEndian.AppendUint32(append(coinPrefix, txHash.Bytes()...), index)
+}
+
+// blockCoinKey = blockCoinPrefix + num (uint64 big endian) + tx hash + index (uint32 big endian)
+func blockCoinKey(number uint64, txHash common.Hash, index uint32) []byte {
+	key := append(append(blockCoinPrefix, encodeBlockNumber(number)...), txHash.Bytes()...)
+	return binary.BigEndian.AppendUint32(key, index)
+}
+
+// coinRootKey = coinRootPrefix + num (uint64 big endian)
+func coinRootKey(number uint64) []byte {
+	return append(coinRootPrefix, encodeBlockNumber(number)...)
+}
+
 // headerKeyPrefix = headerPrefix + num (uint64 big endian)
 func headerKeyPrefix(number uint64) []byte {
 	return append(headerPrefix, encodeBlockNumber(number)...)
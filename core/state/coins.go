@@ -0,0 +1,279 @@
+package state
+
+import (
+	"errors"
+	"execution/common"
+	"execution/common/merkle"
+	"execution/core/rawdb"
+	"execution/ethdb"
+	"execution/rlp"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+var (
+	ErrCoinNotFound = errors.New("coin not found")
+	ErrCoinExists   = errors.New("coin already exists")
+	ErrCoinSpent    = errors.New("coin already spent")
+)
+
+// CoinID identifies a coin by the withdraw transaction creating it and the index
+// of the output coin in it.
+type CoinID struct {
+	TxHash common.Hash
+	Index  uint32
+}
+
+// less orders coin ids like the database index of a block's coins.
+func (id CoinID) less(other CoinID) bool {
+	if id.TxHash != other.TxHash {
+		return id.TxHash.Less(other.TxHash)
+	}
+	return id.Index < other.Index
+}
+
+// Coin is an entry of the coin set. Coins are created by the outputs of withdraw
+// transactions and spent by the inputs of recharge transactions. Spent coins are
+// kept to tell double spends apart from unknown coins.
+type Coin struct {
+	Amount  *big.Int
+	Owner   []byte      // Spending condition of the coin, see gadget.ParseCondition
+	Number  uint64      // Block the coin was created in
+	SpentBy common.Hash // Transaction spending the coin, zero if unspent
+}
+
+// Spent reports whether the coin has been spent.
+func (c *Coin) Spent() bool {
+	return c.SpentBy != (common.Hash{})
+}
+
+func (c *Coin) copy() *Coin {
+	cpy := *c
+	if c.Amount != nil {
+		cpy.Amount = new(big.Int).Set(c.Amount)
+	}
+	cpy.Owner = common.CopyBytes(c.Owner)
+	return &cpy
+}
+
+// coinLeaf is the content of a coin committed to by the coin root of the block
+// creating it. The spent marker is left out, it changes after the fact.
+type coinLeaf struct {
+	TxHash common.Hash
+	Index  uint32
+	Amount *big.Int
+	Owner  []byte
+}
+
+func encodeCoinLeaf(id CoinID, coin *Coin) []byte {
+	enc, _ := rlp.EncodeToBytes(&coinLeaf{TxHash: id.TxHash, Index: id.Index, Amount: coin.Amount, Owner: coin.Owner})
+	return enc
+}
+
+// GetCoin returns a copy of the coin with the given id, or nil if it doesn't
+// exist.
+func (sdb *StateDB) GetCoin(id CoinID) *Coin {
+	if coin := sdb.getCoin(id); coin != nil {
+		return coin.copy()
+	}
+	return nil
+}
+
+func (sdb *StateDB) getCoin(id CoinID) *Coin {
+	if coin, ok := sdb.coins[id]; ok {
+		return coin
+	}
+	coin, err := readCoin(sdb.currentDB.DiskDB(), id)
+	if err != nil {
+		sdb.setError(fmt.Errorf("getCoin (%x, %d) error: %w", id.TxHash, id.Index, err))
+		return nil
+	}
+	if coin != nil {
+		sdb.coins[id] = coin
+	}
+	return coin
+}
+
+// CreateCoin adds a coin created by the output of a withdraw transaction in the
+// current block to the coin set.
+func (sdb *StateDB) CreateCoin(id CoinID, amount *big.Int, owner []byte) error {
+	if sdb.getCoin(id) != nil {
+		return fmt.Errorf("%w: %x, %d", ErrCoinExists, id.TxHash, id.Index)
+	}
+	sdb.journal.append(coinChange{id: id})
+	sdb.setCoin(id, &Coin{Amount: new(big.Int).Set(amount), Owner: common.CopyBytes(owner), Number: sdb.blockNum})
+	return nil
+}
+
+// SpendCoin marks a coin spent by the input of a recharge transaction.
+func (sdb *StateDB) SpendCoin(id CoinID, spender common.Hash) error {
+	coin := sdb.getCoin(id)
+	if coin == nil {
+		return fmt.Errorf("%w: %x, %d", ErrCoinNotFound, id.TxHash, id.Index)
+	}
+	if coin.Spent() {
+		return fmt.Errorf("%w: %x, %d by %x", ErrCoinSpent, id.TxHash, id.Index, coin.SpentBy)
+	}
+	sdb.journal.append(coinChange{id: id, prev: coin})
+
+	spent := coin.copy()
+	spent.SpentBy = spender
+	sdb.setCoin(id, spent)
+	return nil
+}
+
+// setCoin replaces a coin in memory, deleting it if nil.
+func (sdb *StateDB) setCoin(id CoinID, coin *Coin) {
+	if coin == nil {
+		delete(sdb.coins, id)
+	} else {
+		sdb.coins[id] = coin
+	}
+	sdb.coinsDirty[id] = struct{}{}
+}
+
+// commitCoins adds the modified coins to the batch, indexing and rooting the
+// coins created by the current block. It returns the root of those and the
+// encodings of the modified coins, spent markers included, ordered by id.
+func (sdb *StateDB) commitCoins(db ethdb.KeyValueStore, batch ethdb.KeyValueWriter) (common.Hash, [][]byte, error) {
+	if len(sdb.coinsDirty) == 0 {
+		return rawdb.ReadCoinRoot(db, sdb.blockNum), nil, nil
+	}
+	dirty := make([]CoinID, 0, len(sdb.coinsDirty))
+	for id := range sdb.coinsDirty {
+		if _, ok := sdb.coins[id]; ok { // Skip reverted creations
+			dirty = append(dirty, id)
+		}
+	}
+	sort.Slice(dirty, func(i, j int) bool { return dirty[i].less(dirty[j]) })
+
+	// Gather the block's coins already on disk, the root covers them too
+	ids, items, err := blockCoinLeaves(db, sdb.blockNum)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	changes := make([][]byte, 0, len(dirty))
+	for _, id := range dirty {
+		coin := sdb.coins[id]
+		enc, err := rlp.EncodeToBytes(coin)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		if rawdb.ReadCoin(db, id.TxHash, id.Index) == nil {
+			rawdb.WriteBlockCoin(batch, coin.Number, id.TxHash, id.Index)
+			if coin.Number == sdb.blockNum {
+				ids = append(ids, id)
+				items = append(items, encodeCoinLeaf(id, coin))
+			}
+		}
+		rawdb.WriteCoin(batch, id.TxHash, id.Index, enc)
+		changes = append(changes, append(encodeCoinLeaf(id, coin), enc...))
+	}
+	sdb.coinsDirty = make(map[CoinID]struct{})
+
+	if len(items) == 0 {
+		return common.Hash{}, changes, nil
+	}
+	// Leaves are ordered like the block's coin index
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return ids[order[i]].less(ids[order[j]]) })
+	sorted := make([][]byte, len(items))
+	for i, j := range order {
+		sorted[i] = items[j]
+	}
+	root := merkle.Root(sorted)
+	rawdb.WriteCoinRoot(batch, sdb.blockNum, root)
+	return root, changes, nil
+}
+
+// CoinProof proves a committed coin to be created by a block.
+type CoinProof struct {
+	Number uint64        // Block the coin was created in
+	Root   common.Hash   // Root of the coins created by the block
+	Index  int           // Position of the coin in the block's coins
+	Count  int           // Number of coins created by the block
+	Proof  []common.Hash // Merkle proof of the coin under the root
+}
+
+// CoinProof returns the committed coin with the given id and a proof of it being
+// created by its block.
+func (sdb *StateDB) CoinProof(id CoinID) (*Coin, *CoinProof, error) {
+	db := sdb.currentDB.DiskDB()
+	coin, err := readCoin(db, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if coin == nil {
+		return nil, nil, fmt.Errorf("%w: %x, %d", ErrCoinNotFound, id.TxHash, id.Index)
+	}
+	ids, items, err := blockCoinLeaves(db, coin.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := -1
+	for i := range ids {
+		if ids[i] == id {
+			index = i
+		}
+	}
+	proof, err := merkle.Prove(items, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return coin, &CoinProof{
+		Number: coin.Number,
+		Root:   rawdb.ReadCoinRoot(db, coin.Number),
+		Index:  index,
+		Count:  len(items),
+		Proof:  proof,
+	}, nil
+}
+
+// VerifyCoinProof reports whether the proof proves the coin with the given id to
+// be created by the proof's block with the given amount and owner.
+func VerifyCoinProof(id CoinID, coin *Coin, proof *CoinProof) bool {
+	return merkle.Verify(proof.Root, encodeCoinLeaf(id, coin), proof.Index, proof.Count, proof.Proof)
+}
+
+// blockCoinLeaves returns the ids and encoded leaves of the coins created by a
+// block.
+func blockCoinLeaves(db ethdb.KeyValueStore, number uint64) ([]CoinID, [][]byte, error) {
+	var (
+		ids   []CoinID
+		items [][]byte
+		err   error
+	)
+	if iterErr := rawdb.IterateBlockCoins(db, number, func(txHash common.Hash, index uint32) bool {
+		id := CoinID{TxHash: txHash, Index: index}
+
+		var coin *Coin
+		if coin, err = readCoin(db, id); coin == nil && err == nil {
+			err = fmt.Errorf("%w: indexed %x, %d", ErrCoinNotFound, txHash, index)
+		}
+		if err != nil {
+			return false
+		}
+		ids = append(ids, id)
+		items = append(items, encodeCoinLeaf(id, coin))
+		return true
+	}); iterErr != nil {
+		return nil, nil, iterErr
+	}
+	return ids, items, err
+}
+
+func readCoin(db ethdb.KeyValueReader, id CoinID) (*Coin, error) {
+	data := rawdb.ReadCoin(db, id.TxHash, id.Index)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	coin := new(Coin)
+	if err := rlp.DecodeBytes(data, coin); err != nil {
+		return nil, err
+	}
+	return coin, nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+)
+
+// Tests that coins are created and spent once, that reverts undo both, and that
+// committed coins are reloaded and proven against the root of their block.
+func TestCoinSet(t *testing.T) {
+	env := newStateEnv()
+	sdb := env.state
+	sdb.SetBlockInfo(1)
+
+	var (
+		a     = CoinID{TxHash: common.Hash{0x01}, Index: 0}
+		b     = CoinID{TxHash: common.Hash{0x01}, Index: 1}
+		c     = CoinID{TxHash: common.Hash{0x02}, Index: 0}
+		owner = common.Address{0xaa}.Bytes()
+	)
+	for _, id := range []CoinID{a, b, c} {
+		if err := sdb.CreateCoin(id, big.NewInt(int64(id.Index)+10), owner); err != nil {
+			t.Fatalf("failed to create coin %v: %v", id, err)
+		}
+	}
+	if err := sdb.CreateCoin(a, big.NewInt(1), owner); !errors.Is(err, ErrCoinExists) {
+		t.Fatalf("duplicate coin error mismatch: have %v, want %v", err, ErrCoinExists)
+	}
+	// Spend a coin in a reverted snapshot, then for real
+	snap := sdb.Snapshot()
+	if err := sdb.SpendCoin(a, common.Hash{0xff}); err != nil {
+		t.Fatalf("failed to spend coin: %v", err)
+	}
+	if err := sdb.SpendCoin(a, common.Hash{0xfe}); !errors.Is(err, ErrCoinSpent) {
+		t.Fatalf("double spend error mismatch: have %v, want %v", err, ErrCoinSpent)
+	}
+	sdb.RevertToSnapshot(snap)
+	if coin := sdb.GetCoin(a); coin == nil || coin.Spent() {
+		t.Fatalf("reverted spend left coin %+v", coin)
+	}
+	if err := sdb.SpendCoin(a, common.Hash{0xfe}); err != nil {
+		t.Fatalf("failed to spend coin: %v", err)
+	}
+	// Reverted creations aren't committed
+	snap = sdb.Snapshot()
+	if err := sdb.CreateCoin(CoinID{TxHash: common.Hash{0x03}}, big.NewInt(1), owner); err != nil {
+		t.Fatalf("failed to create coin: %v", err)
+	}
+	sdb.RevertToSnapshot(snap)
+
+	if _, err := sdb.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	reloaded, _ := New(NewDatabase(env.currentDB), NewHistoryDB(env.historyDB))
+	if coin := reloaded.GetCoin(a); coin == nil || coin.SpentBy != (common.Hash{0xfe}) {
+		t.Fatalf("spent coin mismatch: have %+v, want spent by %x", coin, common.Hash{0xfe})
+	}
+	if coin := reloaded.GetCoin(CoinID{TxHash: common.Hash{0x03}}); coin != nil {
+		t.Fatalf("reverted coin committed: %+v", coin)
+	}
+	if err := reloaded.SpendCoin(a, common.Hash{0xfd}); !errors.Is(err, ErrCoinSpent) {
+		t.Fatalf("committed double spend error mismatch: have %v, want %v", err, ErrCoinSpent)
+	}
+	// Every coin of the block can be proven, tampered coins can't
+	for _, id := range []CoinID{a, b, c} {
+		coin, proof, err := reloaded.CoinProof(id)
+		if err != nil {
+			t.Fatalf("failed to prove coin %v: %v", id, err)
+		}
+		if proof.Number != 1 || proof.Count != 3 {
+			t.Errorf("coin %v: proof mismatch: have block %d with %d coins, want block 1 with 3", id, proof.Number, proof.Count)
+		}
+		if !VerifyCoinProof(id, coin, proof) {
+			t.Errorf("coin %v: proof failed to verify", id)
+		}
+		coin.Amount = new(big.Int).Add(coin.Amount, common.Big1)
+		if VerifyCoinProof(id, coin, proof) {
+			t.Errorf("coin %v: proof verified tampered amount", id)
+		}
+	}
+	if _, _, err := reloaded.CoinProof(CoinID{TxHash: common.Hash{0x04}}); !errors.Is(err, ErrCoinNotFound) {
+		t.Fatalf("unknown coin proof error mismatch: have %v, want %v", err, ErrCoinNotFound)
+	}
+}
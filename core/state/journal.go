@@ -15,6 +15,11 @@ type journal struct {
 	dirties map[common.Address]int
 }
 
+// length returns the current number of entries in the journal.
+func (j *journal) length() int {
+	return len(j.entries)
+}
+
 func newJournal() *journal {
 	return &journal{
 		dirties: make(map[common.Address]int),
@@ -63,6 +68,12 @@ type (
 		account            *common.Address
 		prevcode, prevhash []byte
 	}
+
+	// Changes to the coin set.
+	coinChange struct {
+		id   CoinID
+		prev *Coin // Nil if the coin was created
+	}
 )
 
 func (ch balanceChange) revert(s *StateDB) {
@@ -96,3 +107,11 @@ func (ch storageChange) revert(s *StateDB) {
 func (ch storageChange) dirtied() *common.Address {
 	return ch.account
 }
+
+func (ch coinChange) revert(s *StateDB) {
+	s.setCoin(ch.id, ch.prev)
+}
+
+func (ch coinChange) dirtied() *common.Address {
+	return nil
+}
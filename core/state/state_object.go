@@ -162,12 +162,9 @@ func (s *stateObject) GetCommittedState(db Database, key common.Hash) common.Has
 
 // GetStorage 取合约状态数据
 func (s *stateObject) GetOriginStorage(addr common.Address, key []byte) ([]byte, error) {
-	value, flag := s.data.Storage[common.BytesToHash(append(addr.Bytes(), key...))]
-	if flag {
-		return value, nil
-	} else {
-		return []byte{}, fmt.Errorf("key not found")
-	}
+	// 未写入过的键即为零值，不视为错误
+	value := s.data.Storage[common.BytesToHash(append(addr.Bytes(), key...))]
+	return value, nil
 }
 
 // SetState 更新key-value状态到数据库（给statedb.go调用）
@@ -183,6 +180,9 @@ func (s *stateObject) SetState(db Database, key, value common.Hash) {
 		prevalue: prev,
 	})
 
+	if s.storageRecord[s.db.txIndex] == nil {
+		s.storageRecord[s.db.txIndex] = make(Storage)
+	}
 	s.storageRecord[s.db.txIndex][key] = value // 记录中间状态（每一笔交易对应）
 
 	s.setState(key, value)
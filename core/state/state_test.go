@@ -45,3 +45,85 @@ func TestNull(t *testing.T) {
 func TestSet(t *testing.T) {
 
 }
+
+// Tests that the root returned by Commit commits to the keys of the written
+// slots along with their values, and to the coins created by the block.
+func TestCommitRoot(t *testing.T) {
+	var (
+		addr = common.Address{0x01}
+		a    = common.Hash{0x0a}
+		b    = common.Hash{0x0b}
+		one  = common.Hash{0x01}
+		two  = common.Hash{0x02}
+	)
+	commit := func(writes map[common.Hash]common.Hash, coins int) common.Hash {
+		sdb := newStateEnv().state
+		sdb.SetBlockInfo(1)
+		for key, value := range writes {
+			sdb.SetState(addr, key, value)
+		}
+		for i := 0; i < coins; i++ {
+			if err := sdb.CreateCoin(CoinID{TxHash: common.Hash{0xff}, Index: uint32(i)}, big.NewInt(1), addr.Bytes()); err != nil {
+				t.Fatalf("failed to create coin: %v", err)
+			}
+		}
+		root, err := sdb.Commit()
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		return root
+	}
+	root := commit(map[common.Hash]common.Hash{a: one, b: two}, 0)
+	if have := commit(map[common.Hash]common.Hash{a: one, b: two}, 0); have != root {
+		t.Fatalf("root mismatch for the same writes: have %x, want %x", have, root)
+	}
+	if have := commit(map[common.Hash]common.Hash{a: two, b: one}, 0); have == root {
+		t.Errorf("root not affected by permuted values")
+	}
+	if have := commit(map[common.Hash]common.Hash{a: one, common.Hash{0x0c}: two}, 0); have == root {
+		t.Errorf("root not affected by keys")
+	}
+	if have := commit(map[common.Hash]common.Hash{a: one, b: two}, 1); have == root {
+		t.Errorf("root not affected by created coins")
+	}
+}
+
+func TestCommitRootSpends(t *testing.T) {
+	var (
+		env = newStateEnv()
+		id  = CoinID{TxHash: common.Hash{0xff}}
+	)
+	env.state.SetBlockInfo(1)
+	if err := env.state.CreateCoin(id, big.NewInt(1), common.Address{0x01}.Bytes()); err != nil {
+		t.Fatalf("failed to create coin: %v", err)
+	}
+	root, err := env.state.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if rawdb.ReadCoinRoot(env.currentDB, 1) == (common.Hash{}) {
+		t.Fatalf("coin root not written")
+	}
+	// A block only spending the coin must not share the root of an empty block
+	commit := func(spend bool) common.Hash {
+		sdb, _ := New(NewDatabase(env.currentDB), NewHistoryDB(env.historyDB))
+		sdb.SetBlockInfo(2)
+		if spend {
+			if err := sdb.SpendCoin(id, common.Hash{0xee}); err != nil {
+				t.Fatalf("failed to spend coin: %v", err)
+			}
+		}
+		root, err := sdb.Commit()
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		return root
+	}
+	empty := commit(false)
+	if have := commit(true); have == empty {
+		t.Errorf("root not affected by spent coins")
+	}
+	if empty == root {
+		t.Errorf("root of an empty block matches the creating block")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"execution/params"
 	"fmt"
 	"math/big"
+	"sort"
 )
 
 type StateDB struct {
@@ -30,7 +31,13 @@ type StateDB struct {
 	// 整合后的写集
 	writeSet map[common.Hash]common.Hash
 
-	journal *journal
+	// 币集合（UTXO）中读写过的币
+	coins      map[CoinID]*Coin
+	coinsDirty map[CoinID]struct{} // 当前区块被修改过的币
+
+	journal        *journal
+	validRevisions []revision
+	nextRevisionId int
 
 	// The Tx Context
 	thash   common.Hash
@@ -55,6 +62,9 @@ func New(currentDB Database, historyDB *HistoryDB) (*StateDB, error) {
 		stateObjects:        make(map[common.Address]*stateObject),
 		stateObjectsPending: make(map[common.Address]struct{}),
 		stateObjectsDirty:   make(map[common.Address]struct{}),
+		coins:               make(map[CoinID]*Coin),
+		coinsDirty:          make(map[CoinID]struct{}),
+		writeSet:            make(map[common.Hash]common.Hash),
 		journal:             newJournal(),
 		accessList:          newAccessList(),
 	}
@@ -74,6 +84,9 @@ func (sdb *StateDB) Copy() *StateDB {
 		stateObjects:        make(map[common.Address]*stateObject, len(sdb.journal.dirties)),
 		stateObjectsPending: make(map[common.Address]struct{}, len(sdb.stateObjectsPending)),
 		stateObjectsDirty:   make(map[common.Address]struct{}, len(sdb.journal.dirties)),
+		coins:               make(map[CoinID]*Coin, len(sdb.coins)),
+		coinsDirty:          make(map[CoinID]struct{}, len(sdb.coinsDirty)),
+		writeSet:            make(map[common.Hash]common.Hash, len(sdb.writeSet)),
 		refund:              sdb.refund,
 		// logs:                 make(map[common.Hash][]*types.Log, len(s.logs)),
 		// logSize:              s.logSize,
@@ -139,6 +152,17 @@ func (sdb *StateDB) Copy() *StateDB {
 	// empty lists, so we do it anyway to not blow up if we ever decide copy them
 	// in the middle of a transaction.
 	state.accessList = sdb.accessList.Copy()
+
+	// Coins are never modified in place, so sharing them is safe
+	for id, coin := range sdb.coins {
+		state.coins[id] = coin
+	}
+	for id := range sdb.coinsDirty {
+		state.coinsDirty[id] = struct{}{}
+	}
+	for key, value := range sdb.writeSet {
+		state.writeSet[key] = value
+	}
 	// state.transientStorage = s.transientStorage.Copy()
 
 	// If there's a prefetcher running, make an inactive copy of it that can
@@ -284,6 +308,7 @@ func (sdb *StateDB) Commit() (common.Hash, error) {
 	if sdb.dbErr != nil {
 		return common.Hash{}, fmt.Errorf("commit aborted due to earlier error")
 	}
+	// 合约代码与币集合的修改写入同一批次，最后一并落盘
 	var codeWriter = sdb.currentDB.DiskDB().NewBatch()
 
 	sdb.Finalise()
@@ -308,10 +333,30 @@ func (sdb *StateDB) Commit() (common.Hash, error) {
 			return common.Hash{}, err
 		}
 	}
-	// 对写集计算哈希根返回
+	// 提交币集合的修改
+	coinRoot, coinChanges, err := sdb.commitCoins(sdb.currentDB.DiskDB(), codeWriter)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := codeWriter.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	// 对写集的键值对、本区块的币集合根及修改的币（含花费标记）计算哈希根返回
+	// 按键排序，保证哈希根与map遍历顺序无关，区块校验依赖于此
+	keys := make([]common.Hash, 0, len(sdb.writeSet))
+	for key := range sdb.writeSet {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+
 	var hashBytes []byte
-	for _, value := range sdb.writeSet {
-		hashBytes = append(hashBytes, value.Bytes()...)
+	for _, key := range keys {
+		hashBytes = append(hashBytes, key.Bytes()...)
+		hashBytes = append(hashBytes, sdb.writeSet[key].Bytes()...)
+	}
+	hashBytes = append(hashBytes, coinRoot.Bytes()...)
+	for _, change := range coinChanges {
+		hashBytes = append(hashBytes, change...)
 	}
 	verifyHash := crypto.Keccak256Hash(hashBytes)
 
@@ -393,32 +438,36 @@ func (sdb *StateDB) CreateAccount(addr common.Address) {
 }
 
 /*
-TODO : 执行与快照相关的操作
+执行与快照相关的操作
 */
-// 暂时置成空函数
+
+type revision struct {
+	id           int
+	journalIndex int
+}
+
 // Snapshot returns an identifier for the current revision of the state.
 func (s *StateDB) Snapshot() int {
-	// id := s.nextRevisionId
-	// s.nextRevisionId++
-	// s.validRevisions = append(s.validRevisions, revision{id, s.journal.length()})
-	// return id
-	return 0
+	id := s.nextRevisionId
+	s.nextRevisionId++
+	s.validRevisions = append(s.validRevisions, revision{id, s.journal.length()})
+	return id
 }
 
 // RevertToSnapshot reverts all state changes made since the given revision.
 func (s *StateDB) RevertToSnapshot(revid int) {
-	// // Find the snapshot in the stack of valid snapshots.
-	// idx := sort.Search(len(s.validRevisions), func(i int) bool {
-	// 	return s.validRevisions[i].id >= revid
-	// })
-	// if idx == len(s.validRevisions) || s.validRevisions[idx].id != revid {
-	// 	panic(fmt.Errorf("revision id %v cannot be reverted", revid))
-	// }
-	// snapshot := s.validRevisions[idx].journalIndex
+	// Find the snapshot in the stack of valid snapshots.
+	idx := sort.Search(len(s.validRevisions), func(i int) bool {
+		return s.validRevisions[i].id >= revid
+	})
+	if idx == len(s.validRevisions) || s.validRevisions[idx].id != revid {
+		panic(fmt.Errorf("revision id %v cannot be reverted", revid))
+	}
+	snapshot := s.validRevisions[idx].journalIndex
 
-	// // Replay the journal to undo changes and remove invalidated snapshots
-	// s.journal.revert(s, snapshot)
-	// s.validRevisions = s.validRevisions[:idx]
+	// Replay the journal to undo changes and remove invalidated snapshots
+	s.journal.revert(s, snapshot)
+	s.validRevisions = s.validRevisions[:idx]
 }
 
 /*
@@ -494,7 +543,7 @@ func (sdb *StateDB) clearJournalAndRefund() {
 		sdb.journal = newJournal()
 		// s.refund = 0
 	}
-	// s.validRevisions = s.validRevisions[:0] // Snapshots can be created without journal entries
+	sdb.validRevisions = sdb.validRevisions[:0] // Snapshots can be created without journal entries
 }
 
 // SetTxContext sets the current transaction hash and index which are
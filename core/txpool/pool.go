@@ -19,7 +19,7 @@ package txpool
 import (
 	"execution/common"
 	instance "execution/core/txpool/pool_instance"
-	"execution/core/types"
+	"execution/types"
 	"fmt"
 	"math/big"
 
@@ -198,6 +198,23 @@ func (p *TxPool) Get(hash common.Hash) *Transaction {
 // to the large transaction churn, add may postpone fully integrating the tx
 // to a later point to batch multiple ones together.
 func (p *TxPool) Add(txs []*Transaction, local bool, sync bool) []error {
+	return p.add(txs, func(subpool SubPool, txs []*Transaction) []error {
+		return subpool.Add(txs, local, sync)
+	})
+}
+
+// AddFromPeer enqueues a batch of remote transactions relayed by the given peer
+// into the subpools accepting them. On top of the checks done by Add, the
+// transactions are subject to the subpools' per-peer rate limits.
+func (p *TxPool) AddFromPeer(txs []*Transaction, peer string, sync bool) []error {
+	return p.add(txs, func(subpool SubPool, txs []*Transaction) []error {
+		return subpool.AddFromPeer(txs, peer, sync)
+	})
+}
+
+// add splits a batch of transactions between the subpools accepting them, adds
+// each part with the given function and returns the errors in the original order.
+func (p *TxPool) add(txs []*Transaction, add func(subpool SubPool, txs []*Transaction) []error) []error {
 	// Split the input transactions between the subpools. It shouldn't really
 	// happen that we receive merged batches, but better graceful than strange
 	// errors.
@@ -224,7 +241,7 @@ func (p *TxPool) Add(txs []*Transaction, local bool, sync bool) []error {
 	// back the errors into the original sort order.
 	errsets := make([][]error, len(p.subpools))
 	for i := 0; i < len(p.subpools); i++ {
-		errsets[i] = p.subpools[i].Add(txsets[i], local, sync)
+		errsets[i] = add(p.subpools[i], txsets[i])
 	}
 	errs := make([]error, len(txs))
 	for i, split := range splits {
@@ -252,6 +269,18 @@ func (p *TxPool) Pending(enforceTips bool) map[common.Address][]*types.Transacti
 	return txs
 }
 
+// PendingParallel packs the currently processable transactions into a block of
+// at most gasLimit gas, split into the given number of non-conflicting lanes for
+// parallel execution. Transactions are picked in the order of the subpool's
+// ordering policy; only the packed ones are taken out of the pool.
+func (p *TxPool) PendingParallel(enforceTips bool, baseFee *big.Int, gasLimit uint64, lanes int) *instance.PackedBlock {
+	// Since (for now) all transactions live in the first subpool, pack from it
+	if len(p.subpools) == 0 {
+		return instance.PackParallel(nil, nil, baseFee, gasLimit, lanes)
+	}
+	return p.subpools[0].PendingParallel(enforceTips, baseFee, gasLimit, lanes)
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and starts sending
 // events to the given channel.
 // func (p *TxPool) SubscribeNewTxsEvent(ch chan<- instance.NewTxsEvent) event.Subscription {
@@ -322,6 +351,40 @@ func (p *TxPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*type
 	return []*types.Transaction{}, []*types.Transaction{}
 }
 
+// Inspect returns a diagnostic snapshot of every account with transactions in
+// the pool: transaction counts, the first nonce gap, cumulative costs, slot usage,
+// last heartbeat, local flag and priority lane.
+func (p *TxPool) Inspect() map[common.Address]*instance.AccountStatus {
+	accounts := make(map[common.Address]*instance.AccountStatus)
+	for _, subpool := range p.subpools {
+		for addr, status := range subpool.Inspect() {
+			accounts[addr] = status
+		}
+	}
+	return accounts
+}
+
+// LaneStats returns a snapshot of the pool capacity used by every priority lane
+// of the subpools, keyed by lane name. Lanes of the same name in several
+// subpools are reported together.
+func (p *TxPool) LaneStats() map[string]*instance.LaneStatus {
+	lanes := make(map[string]*instance.LaneStatus)
+	for _, subpool := range p.subpools {
+		for name, status := range subpool.LaneStats() {
+			total, ok := lanes[name]
+			if !ok {
+				total = new(instance.LaneStatus)
+				lanes[name] = total
+			}
+			total.Pending += status.Pending
+			total.Queued += status.Queued
+			total.Slots += status.Slots
+			total.Reserved += status.Reserved
+		}
+	}
+	return lanes
+}
+
 // Locals retrieves the accounts currently considered local by the pool.
 func (p *TxPool) Locals() []common.Address {
 	// Retrieve the locals from each subpool and deduplicate them
@@ -3,50 +3,266 @@ package txpool_instance
 import (
 	"errors"
 	"math/big"
+
+	"golang.org/x/exp/constraints"
 )
 
 var (
 	ErrEmptyTree = errors.New("empty tree")
 )
 
-// AVLTree structure. Public methods are Add, Remove, Update, Search, Flatten.
-type AVLTree struct {
-	root *AVLNode
+// Aggregate is an associative operation over tree values with an identity
+// element, maintained for every subtree to answer range queries in O(log n).
+// Implementations are expected to be stateless, as the tree only ever uses the
+// zero value of the type.
+type Aggregate[V any] interface {
+	// Zero returns the identity element of the aggregate.
+	Zero() V
+
+	// Combine returns the aggregate of a followed by b. It must not modify its
+	// arguments.
+	Combine(a, b V) V
+}
+
+// BigSum aggregates big integers by summing them.
+type BigSum struct{}
+
+func (BigSum) Zero() *big.Int { return new(big.Int) }
+
+func (BigSum) Combine(a, b *big.Int) *big.Int { return new(big.Int).Add(a, b) }
+
+// CostTree is the AVL tree of transaction costs indexed by nonce used by the
+// sorted maps of the pool.
+type CostTree = AVLTree[uint64, *big.Int, BigSum]
+
+// AVLTree is a balanced binary search tree mapping ordered keys to values, which
+// also maintains the aggregate of the values of every subtree and its size. This
+// allows prefix and range aggregates, order statistics and aggregate bounded
+// searches in O(log n). The zero value is an empty tree ready to use.
+type AVLTree[K constraints.Ordered, V any, A Aggregate[V]] struct {
+	root *AVLNode[K, V]
+	agg  A
+}
+
+// AVLNode is a single entry of an AVL tree.
+type AVLNode[K constraints.Ordered, V any] struct {
+	key   K // nonce
+	value V // cost
+	sum   V // Aggregate of the values of the subtree
+
+	// height counts nodes (not edges)
+	height int
+	size   int // Number of nodes in the subtree
+	left   *AVLNode[K, V]
+	right  *AVLNode[K, V]
+}
+
+// Key returns the key of the node.
+func (n *AVLNode[K, V]) Key() K { return n.key }
+
+// Value returns the value stored in the node.
+func (n *AVLNode[K, V]) Value() V { return n.value }
+
+// Len returns the number of entries in the tree.
+func (t *AVLTree[K, V, A]) Len() int {
+	return t.root.getSize()
+}
+
+// Add inserts the value under the given key, replacing any existing one.
+func (t *AVLTree[K, V, A]) Add(key K, value V) {
+	t.root = t.add(t.root, key, value)
+}
+
+// Remove deletes the entry with the given key, if any.
+func (t *AVLTree[K, V, A]) Remove(key K) {
+	t.root = t.remove(t.root, key)
+}
+
+// Update moves the entry at oldKey to newKey with a new value.
+func (t *AVLTree[K, V, A]) Update(oldKey K, newKey K, newValue V) {
+	t.root = t.remove(t.root, oldKey)
+	t.root = t.add(t.root, newKey, newValue)
+}
+
+// Get returns the value stored under the given key.
+func (t *AVLTree[K, V, A]) Get(key K) (V, bool) {
+	for n := t.root; n != nil; {
+		switch {
+		case key < n.key:
+			n = n.left
+		case key > n.key:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	var empty V
+	return empty, false
+}
+
+// Search returns the node with the given key, if any, and the aggregate of all
+// the values with keys up to and including it.
+func (t *AVLTree[K, V, A]) Search(key K) (node *AVLNode[K, V], sum V) {
+	sum = t.agg.Zero()
+	for n := t.root; n != nil; {
+		if key < n.key {
+			n = n.left
+			continue
+		}
+		if n.left != nil {
+			sum = t.agg.Combine(sum, n.left.sum)
+		}
+		sum = t.agg.Combine(sum, n.value)
+		if key == n.key {
+			return n, sum
+		}
+		n = n.right
+	}
+	return nil, sum
+}
+
+// PrefixSum returns the aggregate of all the values with keys up to and
+// including the given one.
+func (t *AVLTree[K, V, A]) PrefixSum(key K) V {
+	_, sum := t.Search(key)
+	return sum
+}
+
+// RangeSum returns the aggregate of all the values with keys in [from, to].
+func (t *AVLTree[K, V, A]) RangeSum(from, to K) V {
+	if to < from {
+		return t.agg.Zero()
+	}
+	return t.rangeSum(t.root, from, to, false, false)
+}
+
+// rangeSum aggregates the values of the subtree within [from, to]. The bounded
+// flags signal that the subtree is already known to be above from or below to.
+func (t *AVLTree[K, V, A]) rangeSum(n *AVLNode[K, V], from, to K, aboveFrom, belowTo bool) V {
+	if n == nil {
+		return t.agg.Zero()
+	}
+	if aboveFrom && belowTo {
+		return n.sum
+	}
+	if !aboveFrom && n.key < from {
+		return t.rangeSum(n.right, from, to, false, belowTo)
+	}
+	if !belowTo && n.key > to {
+		return t.rangeSum(n.left, from, to, aboveFrom, false)
+	}
+	sum := t.rangeSum(n.left, from, to, aboveFrom, true)
+	sum = t.agg.Combine(sum, n.value)
+	return t.agg.Combine(sum, t.rangeSum(n.right, from, to, true, belowTo))
+}
+
+// Kth returns the k-th smallest entry of the tree, counting from zero.
+func (t *AVLTree[K, V, A]) Kth(k int) (*AVLNode[K, V], bool) {
+	if k < 0 || k >= t.Len() {
+		return nil, false
+	}
+	n := t.root
+	for {
+		switch left := n.left.getSize(); {
+		case k < left:
+			n = n.left
+		case k > left:
+			k -= left + 1
+			n = n.right
+		default:
+			return n, true
+		}
+	}
+}
+
+// MaxPrefix returns the largest key for which fn holds on the aggregate of all
+// values up to and including that key. The predicate must be monotone, i.e. once
+// it fails for a key it must fail for all larger keys, such as checking that a
+// running sum of non-negative costs stays within a budget.
+func (t *AVLTree[K, V, A]) MaxPrefix(fn func(sum V) bool) (*AVLNode[K, V], bool) {
+	var (
+		best *AVLNode[K, V]
+		acc  = t.agg.Zero()
+	)
+	for n := t.root; n != nil; {
+		sum := acc
+		if n.left != nil {
+			sum = t.agg.Combine(sum, n.left.sum)
+		}
+		sum = t.agg.Combine(sum, n.value)
+		if fn(sum) {
+			best, acc = n, sum
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return best, best != nil
 }
 
-func (t *AVLTree) Add(key uint64, value *big.Int) {
-	t.root = t.root.add(key, value)
+// Ascend calls fn for every entry of the tree in key order, until fn returns
+// false.
+func (t *AVLTree[K, V, A]) Ascend(fn func(key K, value V) bool) {
+	t.root.ascend(fn)
 }
 
-func (t *AVLTree) Remove(key uint64) {
-	t.root = t.root.remove(key)
+// AscendRange calls fn for every entry with a key in [from, to] in key order,
+// until fn returns false.
+func (t *AVLTree[K, V, A]) AscendRange(from, to K, fn func(key K, value V) bool) {
+	t.root.ascendRange(from, to, fn)
 }
 
-func (t *AVLTree) Update(oldKey uint64, newKey uint64, newValue *big.Int) {
-	t.root = t.root.remove(oldKey)
-	t.root = t.root.add(newKey, newValue)
+// Split divides the tree into the entries with keys lower than the given one,
+// which remain in t, and the rest, which are returned as a new tree.
+func (t *AVLTree[K, V, A]) Split(key K) *AVLTree[K, V, A] {
+	left, right := t.split(t.root, key)
+	t.root = left
+	return &AVLTree[K, V, A]{root: right}
 }
 
-func (t *AVLTree) Search(key uint64) (node *AVLNode, sum *big.Int) {
-	return t.root.search(key)
+// Merge moves all the entries of other into t, leaving other empty. If the key
+// ranges of the trees don't overlap they are joined in O(log n), otherwise the
+// entries of other are inserted one by one, replacing the values of t on equal
+// keys.
+func (t *AVLTree[K, V, A]) Merge(other *AVLTree[K, V, A]) {
+	switch {
+	case other.root == nil:
+	case t.root == nil:
+		t.root = other.root
+	case t.root.findLargest().key < other.root.findSmallest().key:
+		t.root = t.concat(t.root, other.root)
+	case other.root.findLargest().key < t.root.findSmallest().key:
+		t.root = t.concat(other.root, t.root)
+	default:
+		other.Ascend(func(key K, value V) bool {
+			t.Add(key, value)
+			return true
+		})
+	}
+	other.root = nil
 }
 
-func (t *AVLTree) Smallest() (uint64, error) {
+// Smallest returns the lowest key of the tree.
+func (t *AVLTree[K, V, A]) Smallest() (K, error) {
 	if t.root == nil {
-		return 0, ErrEmptyTree
+		var empty K
+		return empty, ErrEmptyTree
 	}
 	return t.root.findSmallest().key, nil // might get error if root is nil
 }
 
-func (t *AVLTree) Largest() (uint64, error) {
+// Largest returns the highest key of the tree.
+func (t *AVLTree[K, V, A]) Largest() (K, error) {
 	if t.root == nil {
-		return 0, ErrEmptyTree
+		var empty K
+		return empty, ErrEmptyTree
 	}
 	return t.root.findLargest().key, nil // might get error if root is nil
 }
 
-func (t *AVLTree) Flatten() []*AVLNode {
-	nodes := make([]*AVLNode, 0)
+// Flatten returns all the nodes of the tree in key order.
+func (t *AVLTree[K, V, A]) Flatten() []*AVLNode[K, V] {
+	nodes := make([]*AVLNode[K, V], 0, t.Len())
 	if t.root == nil {
 		return nodes
 	}
@@ -54,59 +270,31 @@ func (t *AVLTree) Flatten() []*AVLNode {
 	return nodes
 }
 
-// AVLNode structure
-type AVLNode struct {
-	key   uint64   // nonce
-	value *big.Int // cost
-	sum   *big.Int // Sum of costs of the subtree
-
-	// height counts nodes (not edges)
-	height int
-	left   *AVLNode
-	right  *AVLNode
-}
-
 // Adds a new node
-func (n *AVLNode) add(key uint64, value *big.Int) *AVLNode {
+func (t *AVLTree[K, V, A]) add(n *AVLNode[K, V], key K, value V) *AVLNode[K, V] {
 	if n == nil {
-		newValue := new(big.Int)
-		newSum := new(big.Int)
-		newValue.Add(newValue, value)
-		newSum.Add(newSum, value)
-		return &AVLNode{key, newValue, newSum, 1, nil, nil}
+		return &AVLNode[K, V]{key: key, value: value, sum: value, height: 1, size: 1}
 	}
-
 	if key < n.key {
-		n.left = n.left.add(key, value)
-
+		n.left = t.add(n.left, key, value)
 	} else if key > n.key {
-		n.right = n.right.add(key, value)
+		n.right = t.add(n.right, key, value)
 	} else {
 		// if same key exists update value
-		new := new(big.Int)
-		n.value = new.Add(value, new)
+		n.value = value
 	}
-
-	new := new(big.Int)
-	n.sum = new.Add(n.value, new)
-	if n.left != nil {
-		n.sum = n.sum.Add(n.sum, n.left.sum)
-	}
-	if n.right != nil {
-		n.sum = n.sum.Add(n.sum, n.right.sum)
-	}
-	return n.rebalanceTree()
+	return t.rebalanceTree(n)
 }
 
 // Removes a node
-func (n *AVLNode) remove(key uint64) *AVLNode {
+func (t *AVLTree[K, V, A]) remove(n *AVLNode[K, V], key K) *AVLNode[K, V] {
 	if n == nil {
 		return nil
 	}
 	if key < n.key {
-		n.left = n.left.remove(key)
+		n.left = t.remove(n.left, key)
 	} else if key > n.key {
-		n.right = n.right.remove(key)
+		n.right = t.remove(n.right, key)
 	} else {
 		if n.left != nil && n.right != nil {
 			// node to delete found with both children;
@@ -115,7 +303,7 @@ func (n *AVLNode) remove(key uint64) *AVLNode {
 			n.key = rightMinNode.key
 			n.value = rightMinNode.value
 			// delete smallest node that we replaced
-			n.right = n.right.remove(rightMinNode.key)
+			n.right = t.remove(n.right, rightMinNode.key)
 		} else if n.left != nil {
 			// node only has left child
 			n = n.left
@@ -124,48 +312,82 @@ func (n *AVLNode) remove(key uint64) *AVLNode {
 			n = n.right
 		} else {
 			// node has no children
-			n = nil
-			return n
+			return nil
 		}
 	}
-	new := new(big.Int)
-	n.sum = new.Add(n.value, new)
-	if n.left != nil {
-		n.sum = n.sum.Add(n.sum, n.left.sum)
+	return t.rebalanceTree(n)
+}
+
+// join builds a balanced tree out of the left subtree, the middle node and the
+// right subtree, with all keys of left lower than mid and all keys of right
+// higher than it.
+func (t *AVLTree[K, V, A]) join(left *AVLNode[K, V], mid *AVLNode[K, V], right *AVLNode[K, V]) *AVLNode[K, V] {
+	switch lh, rh := left.getHeight(), right.getHeight(); {
+	case lh > rh+1:
+		left.right = t.join(left.right, mid, right)
+		return t.rebalanceTree(left)
+	case rh > lh+1:
+		right.left = t.join(left, mid, right.left)
+		return t.rebalanceTree(right)
+	default:
+		mid.left, mid.right = left, right
+		t.recalculate(mid)
+		return mid
 	}
-	if n.right != nil {
-		n.sum = n.sum.Add(n.sum, n.right.sum)
+}
+
+// concat joins two trees with all keys of left lower than all keys of right.
+func (t *AVLTree[K, V, A]) concat(left *AVLNode[K, V], right *AVLNode[K, V]) *AVLNode[K, V] {
+	mid := left.findLargest()
+	left = t.remove(left, mid.key)
+	return t.join(left, &AVLNode[K, V]{key: mid.key, value: mid.value}, right)
+}
+
+// split divides the subtree into the nodes with keys lower than the given one
+// and the rest.
+func (t *AVLTree[K, V, A]) split(n *AVLNode[K, V], key K) (*AVLNode[K, V], *AVLNode[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	left, right := n.left, n.right
+	n.left, n.right = nil, nil
+
+	switch {
+	case key < n.key:
+		ll, lr := t.split(left, key)
+		return ll, t.join(lr, n, right)
+	case key > n.key:
+		rl, rr := t.split(right, key)
+		return t.join(left, n, rl), rr
+	default:
+		return left, t.join(nil, n, right)
 	}
-	return n.rebalanceTree()
 }
 
-// Searches for a node
-func (n *AVLNode) search(key uint64) (*AVLNode, *big.Int) {
+func (n *AVLNode[K, V]) ascend(fn func(key K, value V) bool) bool {
 	if n == nil {
-		return nil, big.NewInt(0)
+		return true
 	}
-	if key < n.key {
-		return n.left.search(key)
-	} else if key > n.key {
-		node, sum := n.right.search(key)
-		if n.left != nil {
-			sum = sum.Add(sum, n.left.sum)
-		}
-		sum = sum.Add(sum, n.value)
-		return node, sum
+	return n.left.ascend(fn) && fn(n.key, n.value) && n.right.ascend(fn)
+}
 
-	} else {
-		new := new(big.Int)
-		new.Add(new, n.value)
-		if n.left != nil {
-			return n, new.Add(new, n.left.sum)
-		} else {
-			return n, new
-		}
+func (n *AVLNode[K, V]) ascendRange(from, to K, fn func(key K, value V) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.key > from && !n.left.ascendRange(from, to, fn) {
+		return false
+	}
+	if n.key >= from && n.key <= to && !fn(n.key, n.value) {
+		return false
+	}
+	if n.key < to {
+		return n.right.ascendRange(from, to, fn)
 	}
+	return true
 }
 
-func (n *AVLNode) displayNodesInOrder(nodes *[]*AVLNode) {
+func (n *AVLNode[K, V]) displayNodesInOrder(nodes *[]*AVLNode[K, V]) {
 	if n.left != nil {
 		n.left.displayNodesInOrder(nodes)
 	}
@@ -175,90 +397,84 @@ func (n *AVLNode) displayNodesInOrder(nodes *[]*AVLNode) {
 	}
 }
 
-func (n *AVLNode) getHeight() int {
+func (n *AVLNode[K, V]) getHeight() int {
 	if n == nil {
 		return 0
 	}
 	return n.height
 }
 
-func (n *AVLNode) recalculateHeight() {
+func (n *AVLNode[K, V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// recalculate refreshes the height, size and aggregate of a node from its
+// children.
+func (t *AVLTree[K, V, A]) recalculate(n *AVLNode[K, V]) {
 	n.height = 1 + max(n.left.getHeight(), n.right.getHeight())
+	n.size = 1 + n.left.getSize() + n.right.getSize()
+
+	n.sum = n.value
+	if n.left != nil {
+		n.sum = t.agg.Combine(n.left.sum, n.sum)
+	}
+	if n.right != nil {
+		n.sum = t.agg.Combine(n.sum, n.right.sum)
+	}
 }
 
 // Checks if node is balanced and rebalance
-func (n *AVLNode) rebalanceTree() *AVLNode {
+func (t *AVLTree[K, V, A]) rebalanceTree(n *AVLNode[K, V]) *AVLNode[K, V] {
 	if n == nil {
 		return n
 	}
-	n.recalculateHeight()
+	t.recalculate(n)
 
 	// check balance factor and rotateLeft if right-heavy and rotateRight if left-heavy
 	balanceFactor := n.left.getHeight() - n.right.getHeight()
-	if balanceFactor == -2 {
+	if balanceFactor <= -2 {
 		// check if child is left-heavy and rotateRight first
 		if n.right.left.getHeight() > n.right.right.getHeight() {
-			n.right = n.right.rotateRight()
+			n.right = t.rotateRight(n.right)
 		}
-		return n.rotateLeft()
-	} else if balanceFactor == 2 {
+		return t.rotateLeft(n)
+	} else if balanceFactor >= 2 {
 		// check if child is right-heavy and rotateLeft first
 		if n.left.right.getHeight() > n.left.left.getHeight() {
-			n.left = n.left.rotateLeft()
+			n.left = t.rotateLeft(n.left)
 		}
-		return n.rotateRight()
+		return t.rotateRight(n)
 	}
 	return n
 }
 
 // Rotate nodes left to balance node
-func (n *AVLNode) rotateLeft() *AVLNode {
+func (t *AVLTree[K, V, A]) rotateLeft(n *AVLNode[K, V]) *AVLNode[K, V] {
 	newRoot := n.right
-	new := new(big.Int)
-	temp := new.Add(new, n.sum)
-
-	if n.right != nil {
-		n.sum = n.sum.Sub(n.sum, n.right.sum)
-	}
-
 	n.right = newRoot.left
-
-	if n.right != nil {
-		n.sum = n.sum.Add(n.sum, n.right.sum)
-	}
-
 	newRoot.left = n
-	newRoot.sum = temp
-	n.recalculateHeight()
-	newRoot.recalculateHeight()
+
+	t.recalculate(n)
+	t.recalculate(newRoot)
 	return newRoot
 }
 
 // Rotate nodes right to balance node
-func (n *AVLNode) rotateRight() *AVLNode {
+func (t *AVLTree[K, V, A]) rotateRight(n *AVLNode[K, V]) *AVLNode[K, V] {
 	newRoot := n.left
-	new := new(big.Int)
-	temp := new.Add(new, n.sum)
-
-	if n.left != nil {
-		n.sum = n.sum.Sub(n.sum, n.left.sum)
-	}
-
 	n.left = newRoot.right
-
-	if n.left != nil {
-		n.sum = n.sum.Add(n.sum, n.left.sum)
-	}
-
 	newRoot.right = n
-	newRoot.sum = temp
-	n.recalculateHeight()
-	newRoot.recalculateHeight()
+
+	t.recalculate(n)
+	t.recalculate(newRoot)
 	return newRoot
 }
 
 // Finds the smallest child (based on the key) for the current node
-func (n *AVLNode) findSmallest() *AVLNode {
+func (n *AVLNode[K, V]) findSmallest() *AVLNode[K, V] {
 	if n.left != nil {
 		return n.left.findSmallest()
 	} else {
@@ -267,7 +483,7 @@ func (n *AVLNode) findSmallest() *AVLNode {
 }
 
 // Finds the largest child (based on the key) for the current node
-func (n *AVLNode) findLargest() *AVLNode {
+func (n *AVLNode[K, V]) findLargest() *AVLNode[K, V] {
 	if n.right != nil {
 		return n.right.findLargest()
 	} else {
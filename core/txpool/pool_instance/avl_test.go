@@ -1,6 +1,7 @@
 package txpool_instance
 
 import (
+	"fmt"
 	"math/big"
 	"math/rand"
 	"sort"
@@ -20,7 +21,7 @@ func TestTree(t *testing.T) {
 	for j := 0; j < 100; j++ {
 		//t.Logf("------------------Test %d--------------------", j)
 		rand.Seed(int64(j))
-		tree := &AVLTree{}
+		tree := &CostTree{}
 		m := make(map[uint64]*big.Int)
 
 		for i := 0; i < nops; i++ {
@@ -93,3 +94,209 @@ func TestTree(t *testing.T) {
 	// tree.Add(8, big.NewInt(8))
 	// tree.Search(8)
 }
+
+// maxAgg aggregates integers by taking their maximum, exercising the tree with
+// a non-invertible aggregate.
+type maxAgg struct{}
+
+func (maxAgg) Zero() int { return -1 }
+
+func (maxAgg) Combine(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// validateTree checks the ordering, balance, size and aggregate invariants of
+// every node of the tree.
+func validateTree[V any, A Aggregate[V]](tree *AVLTree[int, V, A], equal func(a, b V) bool) error {
+	var check func(n *AVLNode[int, V]) error
+	check = func(n *AVLNode[int, V]) error {
+		if n == nil {
+			return nil
+		}
+		if n.left != nil && n.left.findLargest().key >= n.key {
+			return fmt.Errorf("node %d: left subtree out of order", n.key)
+		}
+		if n.right != nil && n.right.findSmallest().key <= n.key {
+			return fmt.Errorf("node %d: right subtree out of order", n.key)
+		}
+		if diff := n.left.getHeight() - n.right.getHeight(); diff < -1 || diff > 1 {
+			return fmt.Errorf("node %d: unbalanced by %d", n.key, diff)
+		}
+		if n.height != 1+max(n.left.getHeight(), n.right.getHeight()) {
+			return fmt.Errorf("node %d: height mismatch", n.key)
+		}
+		if n.size != 1+n.left.getSize()+n.right.getSize() {
+			return fmt.Errorf("node %d: size mismatch", n.key)
+		}
+		var agg A
+		sum := n.value
+		if n.left != nil {
+			sum = agg.Combine(n.left.sum, sum)
+		}
+		if n.right != nil {
+			sum = agg.Combine(sum, n.right.sum)
+		}
+		if !equal(sum, n.sum) {
+			return fmt.Errorf("node %d: aggregate mismatch", n.key)
+		}
+		if err := check(n.left); err != nil {
+			return err
+		}
+		return check(n.right)
+	}
+	return check(tree.root)
+}
+
+// Tests the range aggregate, order statistic, bounded search and iteration
+// queries against a brute force reference.
+func TestTreeQueries(t *testing.T) {
+	rand.Seed(1)
+
+	var (
+		tree = new(AVLTree[int, int, maxAgg])
+		ref  = make(map[int]int)
+	)
+	for i := 0; i < 2000; i++ {
+		k, v := rand.Intn(500), rand.Intn(1000)
+		if rand.Intn(4) == 0 {
+			tree.Remove(k)
+			delete(ref, k)
+		} else {
+			tree.Add(k, v)
+			ref[k] = v
+		}
+	}
+	if err := validateTree(tree, func(a, b int) bool { return a == b }); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]int, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	if tree.Len() != len(keys) {
+		t.Fatalf("length mismatch: have %d, want %d", tree.Len(), len(keys))
+	}
+	for i, k := range keys {
+		if node, ok := tree.Kth(i); !ok || node.Key() != k || node.Value() != ref[k] {
+			t.Fatalf("kth %d mismatch: have %v, want %d", i, node, k)
+		}
+	}
+	if _, ok := tree.Kth(len(keys)); ok {
+		t.Fatalf("kth beyond the tree found")
+	}
+	for i := 0; i < 200; i++ {
+		from, to := rand.Intn(520)-10, rand.Intn(520)-10
+		want := -1
+		for k, v := range ref {
+			if k >= from && k <= to && v > want {
+				want = v
+			}
+		}
+		if have := tree.RangeSum(from, to); have != want {
+			t.Fatalf("range [%d, %d] mismatch: have %d, want %d", from, to, have, want)
+		}
+		var iterated []int
+		tree.AscendRange(from, to, func(key int, _ int) bool {
+			iterated = append(iterated, key)
+			return true
+		})
+		var expected []int
+		for _, k := range keys {
+			if k >= from && k <= to {
+				expected = append(expected, k)
+			}
+		}
+		if fmt.Sprint(iterated) != fmt.Sprint(expected) {
+			t.Fatalf("range [%d, %d] iteration mismatch: have %v, want %v", from, to, iterated, expected)
+		}
+	}
+	// The running maximum is monotone, so it can drive a bounded search
+	for _, limit := range []int{-1, 100, 500, 999} {
+		want, found := 0, false
+		for _, k := range keys {
+			if tree.PrefixSum(k) > limit {
+				break
+			}
+			want, found = k, true
+		}
+		node, ok := tree.MaxPrefix(func(sum int) bool { return sum <= limit })
+		if ok != found || (ok && node.Key() != want) {
+			t.Fatalf("max prefix within %d mismatch: have %v, want %d (%v)", limit, node, want, found)
+		}
+	}
+	// Early termination stops the iteration
+	count := 0
+	tree.Ascend(func(int, int) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Fatalf("iteration not stopped: visited %d", count)
+	}
+}
+
+// Tests that splitting and merging trees keeps all entries and the balance and
+// aggregate invariants.
+func TestTreeSplitMerge(t *testing.T) {
+	equal := func(a, b *big.Int) bool { return a.Cmp(b) == 0 }
+	for seed := int64(0); seed < 50; seed++ {
+		rand.Seed(seed)
+
+		tree := new(AVLTree[int, *big.Int, BigSum])
+		total := new(big.Int)
+		for i := 0; i < rand.Intn(300); i++ {
+			k := rand.Intn(1000)
+			if _, ok := tree.Get(k); !ok {
+				v := big.NewInt(rand.Int63n(1000))
+				tree.Add(k, v)
+				total.Add(total, v)
+			}
+		}
+		size := tree.Len()
+		pivot := rand.Intn(1100) - 50
+
+		right := tree.Split(pivot)
+		for _, part := range []*AVLTree[int, *big.Int, BigSum]{tree, right} {
+			if err := validateTree(part, equal); err != nil {
+				t.Fatalf("seed %d: invalid split tree: %v", seed, err)
+			}
+		}
+		if largest, err := tree.Largest(); err == nil && largest >= pivot {
+			t.Fatalf("seed %d: key %d left of pivot %d", seed, largest, pivot)
+		}
+		if smallest, err := right.Smallest(); err == nil && smallest < pivot {
+			t.Fatalf("seed %d: key %d right of pivot %d", seed, smallest, pivot)
+		}
+		if tree.Len()+right.Len() != size {
+			t.Fatalf("seed %d: split lost entries: have %d+%d, want %d", seed, tree.Len(), right.Len(), size)
+		}
+		// Merge back in a random order, then merge an overlapping tree
+		if rand.Intn(2) == 0 {
+			tree.Merge(right)
+		} else {
+			right.Merge(tree)
+			tree = right
+		}
+		if err := validateTree(tree, equal); err != nil {
+			t.Fatalf("seed %d: invalid merged tree: %v", seed, err)
+		}
+		if tree.Len() != size || tree.RangeSum(-1, 1000).Cmp(total) != 0 {
+			t.Fatalf("seed %d: merged tree mismatch: have %d/%v, want %d/%v", seed, tree.Len(), tree.RangeSum(-1, 1000), size, total)
+		}
+		overlap := new(AVLTree[int, *big.Int, BigSum])
+		for i := 0; i < 20; i++ {
+			overlap.Add(rand.Intn(1000), big.NewInt(1))
+		}
+		tree.Merge(overlap)
+		if err := validateTree(tree, equal); err != nil {
+			t.Fatalf("seed %d: invalid overlap merged tree: %v", seed, err)
+		}
+		if overlap.Len() != 0 {
+			t.Fatalf("seed %d: merged tree not emptied", seed)
+		}
+	}
+}
@@ -14,8 +14,16 @@ var (
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
 	ErrOversizedData        = errors.New("transaction data too big")
 	ErrNegativeValue        = errors.New("negative value")
+	ErrMissingGasPrice      = errors.New("missing gas price")
 	ErrGasLimit             = errors.New("gas limit too high")
-	ErrPriceVeryHigh        = errors.New("gas price too high")
+	ErrFeeCapVeryHigh       = errors.New("max fee per gas higher than 2^256-1")
+	ErrTipVeryHigh          = errors.New("max priority fee per gas higher than 2^256-1")
+	ErrTipAboveFeeCap       = errors.New("max priority fee per gas higher than max fee per gas")
 	ErrInvalidSender        = errors.New("invalid sender")
+	ErrSenderMismatch       = errors.New("recovered sender does not match from")
 	ErrIntrinsicGas         = errors.New("intrinsic gas too low")
+	ErrRateLimited          = errors.New("transaction rate limit exceeded")
+	ErrReplaceTooSoon       = errors.New("transaction replaced too soon")
+	ErrCoinMismatch         = errors.New("input coin does not match coin set")
+	ErrDuplicateInput       = errors.New("input coin spent twice")
 )
@@ -3,7 +3,7 @@ package txpool_instance
 import (
 	"execution/common"
 
-	"execution/core/types"
+	"execution/types"
 )
 
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
@@ -0,0 +1,94 @@
+package txpool_instance
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"execution/common"
+)
+
+// AccountStatus is a diagnostic snapshot of the pooled transactions of a single
+// account, meant to explain why its transactions are or aren't executable.
+type AccountStatus struct {
+	Pending int // Number of executable transactions
+	Queued  int // Number of non-executable transactions
+
+	Nonce    uint64   // Next nonce of the account in the head state
+	FirstGap uint64   // First nonce missing after the state nonce, queued transactions wait on it
+	Balance  *big.Int // Balance of the account in the head state
+
+	PendingCost *big.Int // Cumulative cost of the executable transactions
+	QueuedCost  *big.Int // Cumulative cost of the non-executable transactions
+
+	Slots     int       // Number of pool slots used by the account's transactions
+	Heartbeat time.Time // Last time the account had a transaction promoted or enqueued
+	Local     bool      // Whether the account is exempt from pricing and eviction rules
+	Lane      string    // Name of the priority lane of the account, empty if none
+}
+
+// Inspect returns a diagnostic snapshot of every account with transactions in
+// the pool.
+func (pool *LegacyPool) Inspect() map[common.Address]*AccountStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	accounts := make(map[common.Address]*AccountStatus, len(pool.pending)+len(pool.queue))
+	for addr := range pool.pending {
+		accounts[addr] = pool.inspect(addr)
+	}
+	for addr := range pool.queue {
+		if _, ok := accounts[addr]; !ok {
+			accounts[addr] = pool.inspect(addr)
+		}
+	}
+	return accounts
+}
+
+// InspectFrom returns a diagnostic snapshot of the given account, or nil if it
+// has no transactions in the pool.
+func (pool *LegacyPool) InspectFrom(addr common.Address) *AccountStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if pool.pending[addr] == nil && pool.queue[addr] == nil {
+		return nil
+	}
+	return pool.inspect(addr)
+}
+
+// inspect assembles the diagnostic snapshot of an account. The pool lock must be
+// held.
+func (pool *LegacyPool) inspect(addr common.Address) *AccountStatus {
+	status := &AccountStatus{
+		Nonce:       pool.currentState.GetNonce(addr),
+		Balance:     new(big.Int).Set(pool.currentState.GetBalance(addr)),
+		PendingCost: new(big.Int),
+		QueuedCost:  new(big.Int),
+		Heartbeat:   pool.beats[addr],
+		Local:       pool.locals.contains(addr),
+	}
+	if lane, ok := pool.laneOf(addr); ok {
+		status.Lane = pool.config.Lanes[lane].Name
+	}
+	pending, queue := pool.pending[addr], pool.queue[addr]
+	if pending != nil {
+		status.Pending = pending.Len()
+		status.PendingCost = pending.GetCost(math.MaxUint64)
+		for _, tx := range pending.Flatten() {
+			status.Slots += numSlots(tx)
+		}
+	}
+	if queue != nil {
+		status.Queued = queue.Len()
+		status.QueuedCost = queue.GetCost(math.MaxUint64)
+		for _, tx := range queue.Flatten() {
+			status.Slots += numSlots(tx)
+		}
+	}
+	status.FirstGap = status.Nonce
+	for (pending != nil && pending.Contains(status.FirstGap)) || (queue != nil && queue.Contains(status.FirstGap)) {
+		status.FirstGap++
+	}
+	return status
+}
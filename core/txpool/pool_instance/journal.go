@@ -17,13 +17,19 @@
 package txpool_instance
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 
 	"execution/common"
-	"execution/core/types"
+	"execution/types"
 	"execution/utils"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -33,6 +39,24 @@ import (
 // into the journal, but no such file is currently open.
 var errNoActiveJournal = errors.New("no active journal")
 
+// errJournalCorrupt is returned if a journal record fails its length or checksum
+// validation, most likely due to a crash in the middle of writing it.
+var errJournalCorrupt = errors.New("corrupt journal record")
+
+// journalMagic is the header identifying the binary journal format. Journals
+// without it are parsed as the legacy stream of serialized transactions.
+var journalMagic = []byte{'T', 'X', 'J', 1}
+
+// journalRecordHeader is the size of a record header: the big endian payload
+// length followed by the CRC32-C checksum of the payload.
+const journalRecordHeader = 8
+
+// journalRecordLimit is the maximum payload size accepted for a record, to
+// avoid allocating garbage lengths read from a corrupted journal.
+var journalRecordLimit = 4 * txMaxSize
+
+var journalCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
 // devNull is a WriteCloser that just discards anything written into it. Its
 // goal is to allow the transaction journal to write into a fake journal when
 // loading transactions on startup without printing warnings due to no file
@@ -44,6 +68,10 @@ func (*devNull) Close() error                      { return nil }
 
 // journal is a rotating log of transactions with the aim of storing locally
 // created transactions to allow non-executed ones to survive node restarts.
+//
+// The journal is a sequence of length-prefixed and checksummed records, so a
+// record torn by a crash is detected on load and cut off, keeping all the
+// records before it.
 type journal struct {
 	path   string         // Filesystem path to store the transactions at
 	writer io.WriteCloser // Output stream to write new transactions into
@@ -57,7 +85,9 @@ func newTxJournal(path string) *journal {
 }
 
 // load parses a transaction journal dump from disk, loading its contents into
-// the specified pool.
+// the specified pool. If a corrupted record is found, the journal is truncated
+// to the last valid record and the corruption is returned after loading all
+// the transactions before it.
 func (journal *journal) load(add func(types.Transactions) []error) error {
 	// Open the journal for loading any past transactions
 	input, err := os.Open(journal.path)
@@ -74,44 +104,36 @@ func (journal *journal) load(add func(types.Transactions) []error) error {
 	journal.writer = new(devNull)
 	defer func() { journal.writer = nil }()
 
-	// Inject all transactions from the journal into the pool
-	var serializer utils.JsonSerializer
-	stream := serializer.GetDecoder(input, 0)
 	total, dropped := 0, 0
 
 	// Create a method to load a limited batch of transactions and bump the
 	// appropriate progress counters. Then use this method to load all the
 	// journaled transactions in small-ish batches.
-	loadBatch := func(txs types.Transactions) {
-		for _, err := range add(txs) {
+	var batch types.Transactions
+	loadBatch := func() {
+		for _, err := range add(batch) {
 			if err != nil {
 				log.Debug("Failed to add journaled transaction", "err", err)
 				dropped++
 			}
 		}
+		batch = batch[:0]
 	}
-	var (
-		failure error
-		batch   types.Transactions
-	)
-	for {
-		// Parse the next transaction and terminate on error
-		tx := new(types.Transaction)
-		if err = stream.Decode(tx); err != nil {
-			if err != io.EOF {
-				failure = err
-			}
-			if batch.Len() > 0 {
-				loadBatch(batch)
-			}
-			break
-		}
+	valid, failure := readJournal(input, func(tx *types.Transaction) {
 		// New transaction parsed, queue up for later, import if threshold is reached
 		total++
-
 		if batch = append(batch, tx); batch.Len() > 1024 {
-			loadBatch(batch)
-			batch = batch[:0]
+			loadBatch()
+		}
+	})
+	if batch.Len() > 0 {
+		loadBatch()
+	}
+	if errors.Is(failure, errJournalCorrupt) && valid > 0 {
+		// Cut off the torn tail so later appends start at a record boundary
+		log.Warn("Truncating corrupted transaction journal", "offset", valid, "err", failure)
+		if err := os.Truncate(journal.path, valid); err != nil {
+			log.Warn("Failed to truncate transaction journal", "err", err)
 		}
 	}
 	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
@@ -124,16 +146,12 @@ func (journal *journal) insert(tx *types.Transaction) error {
 	if journal.writer == nil {
 		return errNoActiveJournal
 	}
-	var serializer utils.JsonSerializer
-	Encoder := serializer.GetEncoder(journal.writer)
-	if err := Encoder.Encode(tx); err != nil {
-		return err
-	}
-	return nil
+	return writeJournalRecord(journal.writer, tx)
 }
 
 // rotate regenerates the transaction journal based on the current contents of
-// the transaction pool.
+// the transaction pool. The new journal is written and synced to a temporary
+// file first, and then atomically moved into place.
 func (journal *journal) rotate(all map[common.Address]types.Transactions) error {
 	// Close the current journal (if any is open)
 	if journal.writer != nil {
@@ -147,24 +165,37 @@ func (journal *journal) rotate(all map[common.Address]types.Transactions) error
 	if err != nil {
 		return err
 	}
-	var serializer utils.JsonSerializer
-	Encoder := serializer.GetEncoder(replacement)
+	output := bufio.NewWriter(replacement)
+	if _, err = output.Write(journalMagic); err != nil {
+		replacement.Close()
+		return err
+	}
 	journaled := 0
 	for _, txs := range all {
 		for _, tx := range txs {
-			if err = Encoder.Encode(tx); err != nil {
+			if err = writeJournalRecord(output, tx); err != nil {
 				replacement.Close()
 				return err
 			}
 		}
 		journaled += len(txs)
 	}
+	if err = output.Flush(); err != nil {
+		replacement.Close()
+		return err
+	}
+	if err = replacement.Sync(); err != nil {
+		replacement.Close()
+		return err
+	}
 	replacement.Close()
 
 	// Replace the live journal with the newly generated one
 	if err = os.Rename(journal.path+".new", journal.path); err != nil {
 		return err
 	}
+	syncDir(filepath.Dir(journal.path))
+
 	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
@@ -185,3 +216,165 @@ func (journal *journal) close() error {
 	}
 	return err
 }
+
+// syncDir flushes a directory entry to disk, making a preceding rename durable.
+// Failures are ignored since not every platform supports syncing directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// writeJournalRecord serializes a transaction and writes it as a single record
+// into the given writer.
+func writeJournalRecord(w io.Writer, tx *types.Transaction) error {
+	var (
+		serializer utils.JsonSerializer
+		buf        bytes.Buffer
+	)
+	buf.Write(make([]byte, journalRecordHeader))
+	if err := serializer.GetEncoder(&buf).Encode(tx); err != nil {
+		return err
+	}
+	record := buf.Bytes()
+	payload := record[journalRecordHeader:]
+	if uint64(len(payload)) > journalRecordLimit {
+		return fmt.Errorf("journal record too large: %d bytes, limit %d", len(payload), journalRecordLimit)
+	}
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, journalCRCTable))
+
+	// Write the record in one go to minimize the window for torn writes
+	_, err := w.Write(record)
+	return err
+}
+
+// readJournal parses all transactions in a journal stream, invoking fn for each
+// of them. It returns the offset right after the last valid record and the
+// error that stopped parsing, if any other than reaching the end of the stream.
+func readJournal(input io.Reader, fn func(tx *types.Transaction)) (int64, error) {
+	stream := bufio.NewReader(input)
+
+	magic, err := stream.Peek(len(journalMagic))
+	if err == io.EOF && len(magic) == 0 {
+		return 0, nil // Empty journal
+	}
+	if !bytes.Equal(magic, journalMagic) {
+		return 0, readLegacyJournal(stream, fn)
+	}
+	stream.Discard(len(journalMagic))
+
+	var (
+		serializer utils.JsonSerializer
+		offset     = int64(len(journalMagic))
+		header     = make([]byte, journalRecordHeader)
+	)
+	for {
+		if _, err := io.ReadFull(stream, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return offset, fmt.Errorf("%w: truncated header at offset %d", errJournalCorrupt, offset)
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if uint64(size) > journalRecordLimit {
+			return offset, fmt.Errorf("%w: record size %d at offset %d", errJournalCorrupt, size, offset)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(stream, payload); err != nil {
+			return offset, fmt.Errorf("%w: truncated payload at offset %d", errJournalCorrupt, offset)
+		}
+		if crc32.Checksum(payload, journalCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+			return offset, fmt.Errorf("%w: checksum mismatch at offset %d", errJournalCorrupt, offset)
+		}
+		tx := new(types.Transaction)
+		if err := serializer.GetDecoder(bytes.NewReader(payload), uint64(size), uint64(size)).Decode(tx); err != nil {
+			return offset, fmt.Errorf("%w: undecodable transaction at offset %d: %v", errJournalCorrupt, offset, err)
+		}
+		fn(tx)
+		offset += journalRecordHeader + int64(size)
+	}
+}
+
+// readLegacyJournal parses a journal written as a plain stream of serialized
+// transactions, as done before records were introduced. The next rotation will
+// rewrite it in the record format. Transactions are held to the record limit.
+func readLegacyJournal(input io.Reader, fn func(tx *types.Transaction)) error {
+	var serializer utils.JsonSerializer
+	stream := serializer.GetDecoder(input, 0, journalRecordLimit)
+	for {
+		tx := new(types.Transaction)
+		if err := stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+		fn(tx)
+	}
+}
+
+// JournalReport summarizes the result of verifying a transaction journal.
+type JournalReport struct {
+	Legacy       bool  // Whether the journal is in the legacy stream format
+	Transactions int   // Number of valid transactions in the journal
+	ValidSize    int64 // Size of the valid prefix of the journal
+	Size         int64 // Total size of the journal file
+	Corruption   error // First corruption found, nil if the journal is intact
+}
+
+// VerifyJournal checks the transaction journal at the given path without
+// modifying it, reporting how many records are valid and where the first
+// corruption is, if any.
+func VerifyJournal(path string) (*JournalReport, error) {
+	input, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	stat, err := input.Stat()
+	if err != nil {
+		return nil, err
+	}
+	report := &JournalReport{Size: stat.Size()}
+
+	magic := make([]byte, len(journalMagic))
+	n, _ := input.ReadAt(magic, 0)
+	report.Legacy = n > 0 && !bytes.Equal(magic[:n], journalMagic)
+
+	valid, err := readJournal(input, func(*types.Transaction) { report.Transactions++ })
+	report.ValidSize = valid
+	if report.Legacy && err == nil {
+		report.ValidSize = report.Size
+	}
+	report.Corruption = err
+	return report, nil
+}
+
+// DumpJournal writes every valid transaction in the journal at the given path
+// into w, one serialized transaction per line. Parsing stops at the first
+// corrupted record, whose error is returned.
+func DumpJournal(path string, w io.Writer) error {
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var (
+		serializer utils.JsonSerializer
+		encoder    = serializer.GetEncoder(w)
+		failure    error
+	)
+	_, err = readJournal(input, func(tx *types.Transaction) {
+		if failure == nil {
+			failure = encoder.Encode(tx)
+		}
+	})
+	if failure != nil {
+		return failure
+	}
+	return err
+}
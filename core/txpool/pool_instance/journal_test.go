@@ -0,0 +1,132 @@
+package txpool_instance
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types"
+	"execution/types/gadget"
+	"execution/utils"
+)
+
+// writeTestJournal rotates a journal containing the given transactions into a
+// fresh file and returns its path.
+func writeTestJournal(t *testing.T, txs types.Transactions) string {
+	path := filepath.Join(t.TempDir(), "transactions.rlp")
+	journal := newTxJournal(path)
+	if err := journal.rotate(map[common.Address]types.Transactions{txs[0].From: txs}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	journal.close()
+	return path
+}
+
+func loadTestJournal(t *testing.T, path string) (types.Transactions, error) {
+	var loaded types.Transactions
+	err := newTxJournal(path).load(func(txs types.Transactions) []error {
+		loaded = append(loaded, txs...)
+		return make([]error, len(txs))
+	})
+	return loaded, err
+}
+
+// Tests that a journal with a torn or corrupted tail loads all the transactions
+// before the corruption and is truncated to the last valid record.
+func TestJournalCorruption(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+		pricedTransaction(2, 100000, big.NewInt(1), key),
+	}
+	path := writeTestJournal(t, txs)
+	intact, _ := os.ReadFile(path)
+
+	report, err := VerifyJournal(path)
+	if err != nil {
+		t.Fatalf("failed to verify journal: %v", err)
+	}
+	if report.Transactions != 3 || report.ValidSize != report.Size || report.Corruption != nil || report.Legacy {
+		t.Fatalf("intact journal report mismatch: %+v", report)
+	}
+	// Tear the last record in half and ensure the first two survive
+	os.WriteFile(path, intact[:len(intact)-20], 0644)
+	loaded, err := loadTestJournal(t, path)
+	if !errors.Is(err, errJournalCorrupt) {
+		t.Fatalf("torn journal error mismatch: have %v, want %v", err, errJournalCorrupt)
+	}
+	if len(loaded) != 2 || loaded[1].TxHash != txs[1].TxHash {
+		t.Fatalf("loaded transaction mismatch: have %d, want %d", len(loaded), 2)
+	}
+	if report, _ := VerifyJournal(path); report.Corruption != nil || report.Transactions != 2 {
+		t.Fatalf("torn journal not truncated: %+v", report)
+	}
+	// Flip a payload bit of the second record and ensure the checksum catches it
+	corrupt := append([]byte{}, intact...)
+	corrupt[len(intact)*2/3] ^= 0x01
+	os.WriteFile(path, corrupt, 0644)
+
+	report, _ = VerifyJournal(path)
+	if !errors.Is(report.Corruption, errJournalCorrupt) || report.Transactions >= 3 {
+		t.Fatalf("corrupted journal report mismatch: %+v", report)
+	}
+}
+
+// Tests that journals in the legacy stream format are still loaded and can be
+// inspected.
+func TestJournalLegacy(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+	}
+	var (
+		serializer utils.JsonSerializer
+		buf        bytes.Buffer
+	)
+	for _, tx := range txs {
+		serializer.GetEncoder(&buf).Encode(tx)
+	}
+	path := filepath.Join(t.TempDir(), "transactions.rlp")
+	os.WriteFile(path, buf.Bytes(), 0644)
+
+	loaded, err := loadTestJournal(t, path)
+	if err != nil {
+		t.Fatalf("failed to load legacy journal: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("loaded transaction mismatch: have %d, want %d", len(loaded), 2)
+	}
+	report, _ := VerifyJournal(path)
+	if !report.Legacy || report.Transactions != 2 {
+		t.Fatalf("legacy journal report mismatch: %+v", report)
+	}
+	var dump bytes.Buffer
+	if err := DumpJournal(path, &dump); err != nil {
+		t.Fatalf("failed to dump journal: %v", err)
+	}
+	if !bytes.Equal(dump.Bytes(), buf.Bytes()) {
+		t.Fatalf("journal dump mismatch")
+	}
+	// Legacy transactions are held to the record limit like records are
+	huge := types.NewNormalTransaction(2, common.Address{0x01}, big.NewInt(1), 100000, gadget.NewGasPrice(big.NewInt(1)), make([]byte, journalRecordLimit), testSigner, key)
+	serializer.GetEncoder(&buf).Encode(huge)
+	os.WriteFile(path, buf.Bytes(), 0644)
+
+	if loaded, err = loadTestJournal(t, path); !errors.Is(err, utils.ErrInputLimit) {
+		t.Fatalf("oversized legacy transaction error mismatch: have %v, want %v", err, utils.ErrInputLimit)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("loaded transaction mismatch: have %d, want %d", len(loaded), 2)
+	}
+}
@@ -0,0 +1,165 @@
+package txpool_instance
+
+import (
+	"math/big"
+
+	"execution/common"
+	"execution/types"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Lane is a named group of senders, e.g. system accounts like a bridge relayer,
+// given precedence over everyone else. A lane reserves part of the pending
+// capacity of the pool for its senders, so floods of other senders can't starve
+// them, and may admit them at a different minimum price than the pool.
+//
+// Transactions of lane senders are never evicted to make room for others. Beyond
+// its reservation, a lane competes on price with everyone else for admission to
+// a full pool, but its pending transactions are still truncated to the
+// reservation, as everyone else's are to the rest of GlobalSlots.
+type Lane struct {
+	Name     string           // Name of the lane in logs and stats
+	Senders  []common.Address // Accounts whose transactions use the lane
+	Slots    uint64           // Number of GlobalSlots reserved for the lane
+	MinPrice uint64           // Minimum gas tip of the lane's transactions, replacing the pool's
+}
+
+// LaneStatus is a snapshot of the pool capacity used by a lane.
+type LaneStatus struct {
+	Pending  int    // Number of executable transactions of the lane
+	Queued   int    // Number of non-executable transactions of the lane
+	Slots    int    // Number of pool slots used by the lane's transactions
+	Reserved uint64 // Number of pool slots reserved for the lane
+}
+
+// sanitizeLanes drops the lanes and senders which can't be honoured: lanes
+// without senders, senders already in an earlier lane and reservations above
+// what's left of GlobalSlots.
+func sanitizeLanes(lanes []Lane, globalSlots uint64) []Lane {
+	var (
+		sanitized []Lane
+		seen      = make(map[common.Address]struct{})
+		left      = globalSlots
+	)
+	for _, lane := range lanes {
+		senders := make([]common.Address, 0, len(lane.Senders))
+		for _, addr := range lane.Senders {
+			if _, ok := seen[addr]; ok {
+				log.Warn("Sanitizing duplicate txpool lane sender", "lane", lane.Name, "sender", addr)
+				continue
+			}
+			seen[addr] = struct{}{}
+			senders = append(senders, addr)
+		}
+		if len(senders) == 0 {
+			log.Warn("Sanitizing txpool lane without senders", "lane", lane.Name)
+			continue
+		}
+		lane.Senders = senders
+
+		if lane.Slots > left {
+			log.Warn("Sanitizing invalid txpool lane slots", "lane", lane.Name, "provided", lane.Slots, "updated", left)
+			lane.Slots = left
+		}
+		left -= lane.Slots
+		sanitized = append(sanitized, lane)
+	}
+	return sanitized
+}
+
+// laneOf returns the index of the lane of the given sender.
+func (pool *LegacyPool) laneOf(addr common.Address) (int, bool) {
+	lane, ok := pool.laneIndex[addr]
+	return lane, ok
+}
+
+// inLane reports whether a transaction was sent by a lane sender.
+func (pool *LegacyPool) inLane(tx *types.Transaction) bool {
+	_, ok := pool.laneIndex[tx.From]
+	return ok
+}
+
+// minTip returns the minimum gas tip required from the transactions of the given
+// sender.
+func (pool *LegacyPool) minTip(addr common.Address) *big.Int {
+	if lane, ok := pool.laneOf(addr); ok {
+		return new(big.Int).SetUint64(pool.config.Lanes[lane].MinPrice)
+	}
+	return pool.gasTip.Load()
+}
+
+// laneSlots returns the number of pool slots used by the transactions of a
+// lane. The lookup keeps count of them as transactions come and go.
+func (pool *LegacyPool) laneSlots(lane int) int {
+	return pool.all.LaneSlots(lane)
+}
+
+// unusedReservations returns the number of slots reserved for lanes but not
+// used by them, which no one else may take. The pool lock must be held.
+func (pool *LegacyPool) unusedReservations() uint64 {
+	var unused uint64
+	for i, lane := range pool.config.Lanes {
+		if used := uint64(pool.laneSlots(i)); used < lane.Slots {
+			unused += lane.Slots - used
+		}
+	}
+	return unused
+}
+
+// LaneStats returns a snapshot of the pool capacity used by every lane.
+func (pool *LegacyPool) LaneStats() map[string]*LaneStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	stats := make(map[string]*LaneStatus, len(pool.config.Lanes))
+	for i, lane := range pool.config.Lanes {
+		status := &LaneStatus{
+			Slots:    pool.laneSlots(i),
+			Reserved: lane.Slots,
+		}
+		for _, addr := range lane.Senders {
+			if list := pool.pending[addr]; list != nil {
+				status.Pending += list.Len()
+			}
+			if list := pool.queue[addr]; list != nil {
+				status.Queued += list.Len()
+			}
+		}
+		stats[lane.Name] = status
+	}
+	return stats
+}
+
+// laneOrdering gives the transactions of lanes precedence over everyone else,
+// earlier lanes first, and falls back to the wrapped policy within a lane.
+type laneOrdering struct {
+	OrderingPolicy
+	lanes map[common.Address]int // Lane index of each lane sender
+	count int                    // Number of lanes, the rank of everyone else
+}
+
+func (o laneOrdering) rank(tx *types.Transaction) int {
+	if lane, ok := o.lanes[tx.From]; ok {
+		return lane
+	}
+	return o.count
+}
+
+func (o laneOrdering) Cmp(a, b *types.Transaction, aheadA, aheadB uint64, baseFee *big.Int) int {
+	rankA, rankB := o.rank(a), o.rank(b)
+	switch {
+	case rankA < rankB:
+		return 1
+	case rankA > rankB:
+		return -1
+	}
+	return o.OrderingPolicy.Cmp(a, b, aheadA, aheadB, baseFee)
+}
+
+// arrived forwards arrivals to the wrapped policy if it cares about them.
+func (o laneOrdering) arrived(tx *types.Transaction) {
+	if tracker, ok := o.OrderingPolicy.(arrivalTracker); ok {
+		tracker.arrived(tx)
+	}
+}
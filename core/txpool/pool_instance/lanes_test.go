@@ -0,0 +1,123 @@
+package txpool_instance
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// setupLanePool creates a pool with room for 8 transactions, 2 of them reserved
+// for the lane of the returned relayer key, which may submit free transactions.
+func setupLanePool(t *testing.T) (*LegacyPool, *ecdsa.PrivateKey) {
+	statedb := newStateEnv().state
+	blockchain := NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+	relayer, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 4
+	config.GlobalQueue = 4
+	config.Lanes = []Lane{{
+		Name:    "bridge",
+		Senders: []common.Address{crypto.PubkeyToAddress(relayer.PublicKey)},
+		Slots:   2,
+	}}
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+	testAddBalance(pool, crypto.PubkeyToAddress(relayer.PublicKey), big.NewInt(1000000))
+	return pool, relayer
+}
+
+// Tests that the slots reserved for a lane can't be taken by other senders, and
+// that the lane can use them even with transactions others would be refused for.
+func TestLaneReservation(t *testing.T) {
+	t.Parallel()
+
+	pool, relayer := setupLanePool(t)
+	defer pool.Close()
+
+	// Fill everything but the reservation with well paying transactions
+	for i := 0; i < 6; i++ {
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(10000000))
+		if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(10), key)); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(10000000))
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(10), key)); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("reserved slot taken: have %v, want %v", err, ErrUnderpriced)
+	}
+	// Free lane transactions fit into the reservation, but not beyond it
+	var laneTxs []common.Hash
+	for i := uint64(0); i < 2; i++ {
+		tx := pricedTransaction(i, 100000, big.NewInt(0), relayer)
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add lane transaction %d: %v", i, err)
+		}
+		laneTxs = append(laneTxs, tx.TxHash)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(0), relayer)); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("lane transaction beyond reservation error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	stats := pool.LaneStats()["bridge"]
+	if stats == nil {
+		t.Fatalf("lane missing from stats")
+	}
+	if stats.Pending != 2 || stats.Queued != 0 || stats.Slots != 2 || stats.Reserved != 2 {
+		t.Fatalf("lane stats mismatch: have %+v", stats)
+	}
+	if lane := pool.InspectFrom(crypto.PubkeyToAddress(relayer.PublicKey)).Lane; lane != "bridge" {
+		t.Fatalf("inspected lane mismatch: have %q, want %q", lane, "bridge")
+	}
+	// Removing lane transactions frees their part of the reservation again
+	pool.mu.Lock()
+	pool.removeTx(laneTxs[1], true)
+	pool.mu.Unlock()
+
+	if slots := pool.LaneStats()["bridge"].Slots; slots != 1 {
+		t.Fatalf("lane slots after removal mismatch: have %d, want %d", slots, 1)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(0), relayer)); err != nil {
+		t.Fatalf("failed to add lane transaction into freed reservation: %v", err)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that lane transactions come out of the pool ahead of better paying ones
+// and aren't dropped for paying less than the pool minimum.
+func TestLanePrecedence(t *testing.T) {
+	t.Parallel()
+
+	pool, relayer := setupLanePool(t)
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(10000000))
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(10), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(0), relayer)); err != nil {
+		t.Fatalf("failed to add lane transaction: %v", err)
+	}
+	block := PackParallel(pool.Pending(), pool.Ordering(), nil, 10000000, 1)
+	if block.Len() != 2 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 2)
+	}
+	if from := block.Txs[0].From; from != crypto.PubkeyToAddress(relayer.PublicKey) {
+		t.Fatalf("first packed transaction sender mismatch: have %x, want lane sender", from)
+	}
+}
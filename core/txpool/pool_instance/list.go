@@ -20,7 +20,7 @@ import (
 	"math"
 	"math/big"
 
-	"execution/core/types"
+	"execution/types"
 )
 
 // List is a "List" of transactions belonging to an account, sorted by account
@@ -64,21 +64,23 @@ func (l *List) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transa
 	// If there's an older better transaction, abort
 	old := l.txs.Get(tx.Nonce)
 	if old != nil {
-		oldPrice := old.GasPrice.Price
-		newPrice := tx.GasPrice.Price
-		if oldPrice.Cmp(newPrice) >= 0 {
+		if old.GasPrice.GasFeeCapCmp(tx.GasPrice) >= 0 || old.GasPrice.GasTipCapCmp(tx.GasPrice) >= 0 {
 			return false, nil
 		}
-		// threshold = oldPrice  * (100 + priceBump) / 100
+		// thresholdFeeCap = oldFC  * (100 + priceBump) / 100
 		a := big.NewInt(100 + int64(priceBump))
-		aFee := new(big.Int).Mul(a, oldPrice)
+		aFeeCap := new(big.Int).Mul(a, old.GasPrice.GasFeeCap())
+		aTip := a.Mul(a, old.GasPrice.GasTipCap())
+
+		// thresholdTip    = oldTip * (100 + priceBump) / 100
 		b := big.NewInt(100)
-		thresholdFeeCap := aFee.Div(aFee, b)
+		thresholdFeeCap := aFeeCap.Div(aFeeCap, b)
+		thresholdTip := aTip.Div(aTip, b)
 
 		// We have to ensure that both the new fee cap and tip are higher than the
 		// old ones as well as checking the percentage threshold to ensure that
 		// this is accurate for low (Wei-level) gas price replacements.
-		if newPrice.Cmp(thresholdFeeCap) < 0 {
+		if tx.GasPrice.GasFeeCapIntCmp(thresholdFeeCap) < 0 || tx.GasPrice.GasTipCapIntCmp(thresholdTip) < 0 {
 			return false, nil
 		}
 	}
@@ -111,35 +113,48 @@ func (l *List) Forward(threshold uint64) types.Transactions {
 // a point in calculating all the costs or if the balance covers all. If the threshold
 // is lower than the costgas cap, the caps will be reset to a new high after removing
 // the newly invalidated transactions.
+//
+// Every transaction within the longest nonce prefix whose cumulative cost fits
+// the threshold is affordable on its own, so unless the gas limit dropped, only
+// the transactions beyond that prefix, found by a binary search over the cost
+// index, are checked.
 func (l *List) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions, types.Transactions) {
 	// If all transactions are below the threshold, short circuit
 	if l.costcap.Cmp(costLimit) <= 0 && l.gascap <= gasLimit {
 		return nil, nil
 	}
+	checkGas := l.gascap > gasLimit
 	l.costcap = new(big.Int).Set(costLimit) // Lower the caps to the thresholds
 	l.gascap = gasLimit
 
 	// Filter out all the transactions above the account's funds
-	removed := l.txs.Filter(func(tx *types.Transaction) bool {
-		return tx.GasLimit > gasLimit || tx.Cost().Cmp(costLimit) > 0
+	var (
+		removed types.Transactions
+		from    uint64
+	)
+	if last, ok := l.txs.MaxAffordable(costLimit); ok && !checkGas {
+		if last == math.MaxUint64 {
+			return nil, nil
+		}
+		from = last + 1
+	}
+	l.txs.AscendFrom(from, func(tx *types.Transaction) bool {
+		if tx.GasLimit > gasLimit || tx.Cost().Cmp(costLimit) > 0 {
+			removed = append(removed, tx)
+		}
+		return true
 	})
-
 	if len(removed) == 0 {
 		return nil, nil
 	}
+	for _, tx := range removed {
+		l.txs.Remove(tx.Nonce)
+	}
 	var invalids types.Transactions
 	// If the List was strict, filter anything above the lowest nonce
 	if l.strict {
-		lowest := uint64(math.MaxUint64)
-		for _, tx := range removed {
-			if nonce := tx.Nonce; lowest > nonce {
-				lowest = nonce
-			}
-		}
-		// TODO: we can use LastElement() here, may be more efficient
-		invalids = l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce > lowest })
+		invalids = l.txs.SplitAbove(removed[0].Nonce)
 	}
-	// Reset total cost
 	return removed, invalids
 }
 
@@ -180,6 +195,11 @@ func (l *List) Ready(start uint64, threshold *big.Int) types.Transactions {
 	return txs
 }
 
+// PeekReady returns the transactions Ready would, leaving them in the List.
+func (l *List) PeekReady(start uint64, threshold *big.Int) types.Transactions {
+	return l.txs.PeekReady(start, threshold)
+}
+
 // Len returns the length of the transaction List.
 func (l *List) Len() int {
 	return l.txs.Len()
@@ -197,6 +217,12 @@ func (l *List) Flatten() types.Transactions {
 	return l.txs.Flatten()
 }
 
+// MaxAffordable returns the highest nonce in the List such that the transactions
+// up to and including it cost no more than the given balance in total.
+func (l *List) MaxAffordable(balance *big.Int) (uint64, bool) {
+	return l.txs.MaxAffordable(balance)
+}
+
 // LastElement returns the last element of a flattened List, thus, the
 // transaction with the highest nonce
 func (l *List) LastElement() *types.Transaction {
@@ -0,0 +1,205 @@
+package txpool_instance
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"execution/common"
+	"execution/types"
+	"execution/types/gadget"
+)
+
+// costTransaction creates an unsigned transaction with the given nonce and a
+// cost of gas + value, enough for exercising the cost index.
+func costTransaction(nonce uint64, gas uint64, value int64) *types.Transaction {
+	return &types.Transaction{
+		TxPreface: types.TxPreface{
+			From:     common.Address{0x01},
+			Nonce:    nonce,
+			GasLimit: gas,
+			GasPrice: gadget.NewGasPrice(big.NewInt(1)),
+			Value:    big.NewInt(value),
+		},
+	}
+}
+
+// readyLinear is the reference implementation of Ready, walking the contiguous
+// transactions and summing their costs one by one.
+func readyLinear(m *SortedMap, start uint64, threshold *big.Int) types.Transactions {
+	smallest, err := m.tree.Smallest()
+	if smallest > start || err != nil {
+		return nil
+	}
+	var ready types.Transactions
+	total := new(big.Int).Set(m.items[smallest].Cost())
+	for next := smallest; m.Len() > 0 && smallest == next && total.Cmp(threshold) <= 0; next++ {
+		ready = append(ready, m.items[next])
+		m.Remove(smallest)
+
+		if smallest, err = m.tree.Smallest(); err != nil {
+			break
+		}
+		total.Add(total, m.items[smallest].Cost())
+	}
+	return ready
+}
+
+// filterLinear is the reference implementation of Filter, checking the cost of
+// every transaction in the list.
+func filterLinear(l *List, costLimit *big.Int, gasLimit uint64) (types.Transactions, types.Transactions) {
+	if l.costcap.Cmp(costLimit) <= 0 && l.gascap <= gasLimit {
+		return nil, nil
+	}
+	l.costcap = new(big.Int).Set(costLimit)
+	l.gascap = gasLimit
+
+	var removed types.Transactions
+	for _, tx := range l.Flatten() {
+		if tx.GasLimit > gasLimit || tx.Cost().Cmp(costLimit) > 0 {
+			removed = append(removed, tx)
+			l.txs.Remove(tx.Nonce)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	var invalids types.Transactions
+	if l.strict {
+		lowest := uint64(math.MaxUint64)
+		for _, tx := range removed {
+			if tx.Nonce < lowest {
+				lowest = tx.Nonce
+			}
+		}
+		invalids = l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce > lowest })
+	}
+	return removed, invalids
+}
+
+// randomList creates a list of transactions with random costs and nonce gaps.
+func randomList(strict bool, n int, gaps bool) *List {
+	list := NewList(strict)
+	nonce := uint64(0)
+	for i := 0; i < n; i++ {
+		list.Add(costTransaction(nonce, 21000+uint64(rand.Intn(1000)), rand.Int63n(100000)), DefaultConfig.PriceBump)
+		nonce++
+		if gaps && rand.Intn(10) == 0 {
+			nonce++
+		}
+	}
+	return list
+}
+
+func sameNonces(a, b types.Transactions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uint64]bool, len(a))
+	for _, tx := range a {
+		seen[tx.Nonce] = true
+	}
+	for _, tx := range b {
+		if !seen[tx.Nonce] {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests that promotions and demotions through the cost index pick the exact same
+// transactions as walking the lists.
+func TestListAffordability(t *testing.T) {
+	for i := 0; i < 500; i++ {
+		rand.Seed(int64(i))
+		var (
+			strict  = rand.Intn(2) == 0
+			n       = 1 + rand.Intn(100)
+			gaps    = rand.Intn(2) == 0
+			balance = big.NewInt(rand.Int63n(int64(n) * 130000))
+			gas     = uint64(21000 + rand.Intn(1200))
+		)
+		// Run both implementations over identical lists
+		seed := rand.Int63()
+		rand.Seed(seed)
+		have := randomList(strict, n, gaps)
+		rand.Seed(seed)
+		want := randomList(strict, n, gaps)
+
+		removed, invalids := have.Filter(balance, gas)
+		wantRemoved, wantInvalids := filterLinear(want, balance, gas)
+		if !sameNonces(removed, wantRemoved) || !sameNonces(invalids, wantInvalids) {
+			t.Fatalf("run %d: filter mismatch: have %d/%d, want %d/%d", i, len(removed), len(invalids), len(wantRemoved), len(wantInvalids))
+		}
+		ready := have.Ready(0, balance)
+		wantReady := readyLinear(want.txs, 0, balance)
+		if !sameNonces(ready, wantReady) {
+			t.Fatalf("run %d: ready mismatch: have %d, want %d", i, len(ready), len(wantReady))
+		}
+		if have.Len() != want.Len() {
+			t.Fatalf("run %d: remaining mismatch: have %d, want %d", i, have.Len(), want.Len())
+		}
+	}
+}
+
+// Benchmarks the promotion of an affordable nonce prefix out of a large list.
+// The promoted transactions are put back after each round, which costs the same
+// for both implementations.
+func BenchmarkListReady(b *testing.B) {
+	benchmarkListReady(b, func(l *List, balance *big.Int) types.Transactions { return l.Ready(0, balance) })
+}
+
+func BenchmarkListReadyLinear(b *testing.B) {
+	benchmarkListReady(b, func(l *List, balance *big.Int) types.Transactions { return readyLinear(l.txs, 0, balance) })
+}
+
+func benchmarkListReady(b *testing.B, ready func(l *List, balance *big.Int) types.Transactions) {
+	rand.Seed(1)
+	var (
+		list    = randomList(false, 1000, false)
+		balance = list.txs.tree.PrefixSum(499)
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range ready(list, balance) {
+			list.txs.Put(tx)
+		}
+	}
+}
+
+// Benchmarks the demotion of the few expensive transactions at the tail of a
+// large list after a balance drop. The dropped transactions are put back after each round, which
+// costs the same for both implementations.
+func BenchmarkListFilter(b *testing.B) {
+	benchmarkListFilter(b, func(l *List, balance *big.Int) (types.Transactions, types.Transactions) {
+		return l.Filter(balance, math.MaxUint64)
+	})
+}
+
+func BenchmarkListFilterLinear(b *testing.B) {
+	benchmarkListFilter(b, func(l *List, balance *big.Int) (types.Transactions, types.Transactions) {
+		return filterLinear(l, balance, math.MaxUint64)
+	})
+}
+
+func benchmarkListFilter(b *testing.B, filter func(l *List, balance *big.Int) (types.Transactions, types.Transactions)) {
+	rand.Seed(1)
+	list := randomList(true, 990, false)
+	for nonce := uint64(990); nonce < 1000; nonce++ {
+		list.Add(costTransaction(nonce, 21000, math.MaxInt64), DefaultConfig.PriceBump)
+	}
+	var (
+		costcap = list.costcap
+		gascap  = list.gascap
+		balance = list.txs.tree.PrefixSum(989)
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		removed, invalids := filter(list, balance)
+		for _, tx := range append(removed, invalids...) {
+			list.txs.Put(tx)
+		}
+		list.costcap, list.gascap = costcap, gascap
+	}
+}
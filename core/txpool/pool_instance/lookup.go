@@ -5,8 +5,8 @@ import (
 	"sync"
 
 	"execution/common"
-	"execution/core/types"
 	"execution/log"
+	"execution/types"
 )
 
 type accountSet struct {
@@ -87,6 +87,9 @@ type Lookup struct {
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	lanes     map[common.Address]int // Lane index of each lane sender
+	laneSlots []int                  // Number of slots used by each lane
 }
 
 // newLookup returns a new Lookup structure.
@@ -171,6 +174,24 @@ func (t *Lookup) RemoteCount() int {
 	return len(t.remotes)
 }
 
+// trackLanes makes the Lookup count the slots used by each lane, given the lane
+// index of every lane sender. It must be called before any transaction is added.
+func (t *Lookup) trackLanes(lanes map[common.Address]int, count int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lanes = lanes
+	t.laneSlots = make([]int, count)
+}
+
+// LaneSlots returns the current number of slots used by the given lane.
+func (t *Lookup) LaneSlots(lane int) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.laneSlots[lane]
+}
+
 // Slots returns the current number of slots used in the Lookup.
 func (t *Lookup) Slots() int {
 	t.lock.RLock()
@@ -186,6 +207,9 @@ func (t *Lookup) Add(tx *types.Transaction, local bool) {
 
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
+	if lane, ok := t.lanes[tx.From]; ok {
+		t.laneSlots[lane] += numSlots(tx)
+	}
 
 	if local {
 		t.locals[tx.TxHash] = tx
@@ -209,6 +233,9 @@ func (t *Lookup) Remove(hash common.Hash) {
 	}
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
+	if lane, ok := t.lanes[tx.From]; ok {
+		t.laneSlots[lane] -= numSlots(tx)
+	}
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
@@ -235,7 +262,7 @@ func (t *Lookup) RemoteToLocals(locals *accountSet) int {
 func (t *Lookup) RemotesBelowTip(threshold *big.Int) types.Transactions {
 	found := make(types.Transactions, 0, 128)
 	t.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
-		if tx.GasPrice.Price.Cmp(threshold) < 0 {
+		if tx.GasPrice.GasTipCapIntCmp(threshold) < 0 {
 			found = append(found, tx)
 		}
 		return true
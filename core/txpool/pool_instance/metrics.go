@@ -16,12 +16,24 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// Metrics for the spam protection rate limiters
+	accountRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/account", nil) // Rejected due to the per-account rate limit
+	peerRateLimitMeter    = metrics.NewRegisteredMeter("txpool/ratelimit/peer", nil)    // Rejected due to the per-peer rate limit
+	replaceRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/replace", nil) // Rejected due to the replacement interval
+	accountBucketGauge    = metrics.NewRegisteredGauge("txpool/ratelimit/accounts", nil)
+	peerBucketGauge       = metrics.NewRegisteredGauge("txpool/ratelimit/peers", nil)
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
+	expiredMeter       = metrics.NewRegisteredMeter("txpool/expired", nil) // Dropped due to their deadline
+
+	// Metrics for the transactions of blocks discarded by chain reorgs
+	reorgReinjectedMeter = metrics.NewRegisteredMeter("txpool/reorg/reinjected", nil)
+	reorgLostMeter       = metrics.NewRegisteredMeter("txpool/reorg/lost", nil) // Refused on reinjection
 
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
@@ -0,0 +1,133 @@
+package txpool_instance
+
+import (
+	"math"
+	"math/big"
+	"sync"
+
+	"execution/common"
+	"execution/common/lru"
+	"execution/types"
+)
+
+// Ordering policies selectable through Config.Ordering.
+const (
+	OrderByFee       = "fee"       // Prefer transactions paying higher tips
+	OrderByArrival   = "arrival"   // Prefer transactions which arrived earlier
+	OrderByFairShare = "fairshare" // Prefer transactions with fewer transactions of the same sender ahead
+)
+
+// OrderingPolicy decides which of two transactions the pool prefers. It is used
+// to pick the remote transactions evicted first when the pool is full, to pick
+// the accounts cut first when the pending set is over its limit and to order
+// pending transactions of different accounts for block production.
+// Transactions of a single account are always kept in nonce order.
+type OrderingPolicy interface {
+	// Cmp returns a positive number if a is preferred over b, a negative one if
+	// b is preferred over a and 0 if neither is. The ahead values are the number
+	// of transactions of the same sender that have to execute before a and b. If
+	// baseFee is nil, the comparison must not depend on it.
+	Cmp(a, b *types.Transaction, aheadA, aheadB uint64, baseFee *big.Int) int
+}
+
+// arrivalTracker is implemented by the policies which need to be told when a
+// transaction enters the pool.
+type arrivalTracker interface {
+	arrived(tx *types.Transaction)
+}
+
+// newOrderingPolicy creates the ordering policy with the given name. Arrivals
+// are remembered for up to capacity transactions.
+func newOrderingPolicy(name string, capacity int) OrderingPolicy {
+	switch name {
+	case OrderByArrival:
+		return newArrivalOrdering(capacity)
+	case OrderByFairShare:
+		return fairShareOrdering{}
+	default:
+		return feeOrdering{}
+	}
+}
+
+// feeOrdering prefers the transactions paying the higher effective tip at the
+// given base fee, then the higher fee cap and lastly the higher tip cap.
+type feeOrdering struct{}
+
+func (feeOrdering) Cmp(a, b *types.Transaction, aheadA, aheadB uint64, baseFee *big.Int) int {
+	if baseFee != nil {
+		// Compare effective tips if baseFee is specified
+		if c := a.GasPrice.EffectiveGasTipCmp(b.GasPrice, baseFee); c != 0 {
+			return c
+		}
+	}
+	// Compare fee caps if baseFee is not specified or effective tips are equal
+	if c := a.GasPrice.GasFeeCapCmp(b.GasPrice); c != 0 {
+		return c
+	}
+	// Compare tips if effective tips and fee caps are equal
+	return a.GasPrice.GasTipCapCmp(b.GasPrice)
+}
+
+// arrivalOrdering prefers the transactions which entered the pool first. A
+// full pool evicts the most recent arrivals and refuses new ones instead of
+// making room for them.
+type arrivalOrdering struct {
+	arrivals *lru.Cache[common.Hash, uint64] // Arrival sequence number of recently seen transactions
+	next     uint64                          // Sequence number of the next arrival
+	lock     sync.Mutex
+}
+
+func newArrivalOrdering(capacity int) *arrivalOrdering {
+	return &arrivalOrdering{
+		arrivals: lru.NewCache[common.Hash, uint64](capacity),
+	}
+}
+
+// arrived records the arrival of a transaction, unless it's already known, e.g.
+// because it's reinjected after a reorg.
+func (o *arrivalOrdering) arrived(tx *types.Transaction) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.arrivals.Contains(tx.TxHash) {
+		return
+	}
+	o.arrivals.Add(tx.TxHash, o.next)
+	o.next++
+}
+
+// arrival returns the arrival sequence number of a transaction. Transactions
+// never seen or long forgotten are treated as arriving just now.
+func (o *arrivalOrdering) arrival(tx *types.Transaction) uint64 {
+	if seq, ok := o.arrivals.Peek(tx.TxHash); ok {
+		return seq
+	}
+	return math.MaxUint64
+}
+
+func (o *arrivalOrdering) Cmp(a, b *types.Transaction, aheadA, aheadB uint64, baseFee *big.Int) int {
+	seqA, seqB := o.arrival(a), o.arrival(b)
+	switch {
+	case seqA < seqB:
+		return 1
+	case seqA > seqB:
+		return -1
+	}
+	return 0
+}
+
+// fairShareOrdering round-robins across senders: it prefers the transactions
+// with fewer transactions of the same sender ahead of them, so every sender
+// gets its first transaction in before any gets its second. Transactions in the
+// same round are ordered by fee.
+type fairShareOrdering struct{}
+
+func (fairShareOrdering) Cmp(a, b *types.Transaction, aheadA, aheadB uint64, baseFee *big.Int) int {
+	switch {
+	case aheadA < aheadB:
+		return 1
+	case aheadA > aheadB:
+		return -1
+	}
+	return feeOrdering{}.Cmp(a, b, aheadA, aheadB, baseFee)
+}
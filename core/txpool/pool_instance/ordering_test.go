@@ -0,0 +1,158 @@
+package txpool_instance
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Tests that pending transactions are packed across accounts in the order of the
+// ordering policy, and in nonce order within an account.
+func TestPackParallelOrdering(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cheap   = newPackerKey(t)
+		pricey  = newPackerKey(t)
+		pending = make(map[common.Address][]*types.Transaction)
+	)
+	for i := uint64(0); i < 3; i++ {
+		pending[cheap.addr] = append(pending[cheap.addr], pricedTransaction(i, 100000, big.NewInt(5), cheap.key))
+	}
+	for i := uint64(0); i < 3; i++ {
+		pending[pricey.addr] = append(pending[pricey.addr], pricedTransaction(i, 100000, big.NewInt(10), pricey.key))
+	}
+	arrival := newArrivalOrdering(16)
+	for _, addr := range []common.Address{cheap.addr, pricey.addr} {
+		for _, tx := range pending[addr] {
+			arrival.arrived(tx)
+		}
+	}
+	tests := []struct {
+		name   string
+		policy OrderingPolicy
+		want   []common.Address
+	}{
+		{"fee", feeOrdering{}, []common.Address{pricey.addr, pricey.addr, pricey.addr, cheap.addr, cheap.addr, cheap.addr}},
+		{"arrival", arrival, []common.Address{cheap.addr, cheap.addr, cheap.addr, pricey.addr, pricey.addr, pricey.addr}},
+		{"fairshare", fairShareOrdering{}, []common.Address{pricey.addr, cheap.addr, pricey.addr, cheap.addr, pricey.addr, cheap.addr}},
+	}
+	for _, tt := range tests {
+		block := PackParallel(pending, tt.policy, nil, 10000000, 1)
+		if block.Len() != len(tt.want) {
+			t.Fatalf("%s: packed transaction count mismatch: have %d, want %d", tt.name, block.Len(), len(tt.want))
+		}
+		nonces := make(map[common.Address]uint64)
+		for i, tx := range block.Txs {
+			if tx.From != tt.want[i] {
+				t.Errorf("%s: transaction %d sender mismatch: have %x, want %x", tt.name, i, tx.From, tt.want[i])
+			}
+			if tx.Nonce != nonces[tx.From] {
+				t.Errorf("%s: transaction %d nonce mismatch: have %d, want %d", tt.name, i, tx.Nonce, nonces[tx.From])
+			}
+			nonces[tx.From]++
+		}
+	}
+}
+
+// Tests that a pool ordering by arrival refuses new remote transactions when full
+// instead of evicting earlier ones, however well they pay.
+func TestArrivalOrderingEviction(t *testing.T) {
+	t.Parallel()
+
+	for _, ordering := range []string{OrderByFee, OrderByArrival} {
+		statedb := newStateEnv().state
+		blockchain := NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+		config := testTxPoolConfig
+		config.GlobalSlots = 2
+		config.GlobalQueue = 2
+		config.Ordering = ordering
+
+		pool := New(config, blockchain)
+		pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+		// Fill the pool with cheap transactions of distinct accounts
+		for i := 0; i < 4; i++ {
+			key, _ := crypto.GenerateKey()
+			testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+			if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+				t.Fatalf("%s: failed to add transaction %d: %v", ordering, i, err)
+			}
+		}
+		// Add an expensive transaction and check whether it made room for itself
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(100000000))
+
+		err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(100), key))
+		switch ordering {
+		case OrderByFee:
+			if err != nil {
+				t.Errorf("%s: failed to add expensive transaction: %v", ordering, err)
+			}
+		case OrderByArrival:
+			if !errors.Is(err, ErrUnderpriced) {
+				t.Errorf("%s: expensive transaction error mismatch: have %v, want %v", ordering, err, ErrUnderpriced)
+			}
+		}
+		if err := validatePoolInternals(pool); err != nil {
+			t.Fatalf("%s: pool internal state corrupted: %v", ordering, err)
+		}
+		pool.Close()
+	}
+}
+
+// Tests that when equally long accounts are over the pending limit, the one whose
+// last transaction is the least preferred by the ordering policy is cut first.
+func TestTruncatePendingOrdering(t *testing.T) {
+	t.Parallel()
+
+	for _, ordering := range []string{OrderByFee, OrderByArrival} {
+		statedb := newStateEnv().state
+		blockchain := NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+		config := testTxPoolConfig
+		config.AccountSlots = 2
+		config.GlobalSlots = 5
+		config.Ordering = ordering
+
+		pool := New(config, blockchain)
+		pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+		// The pricey account arrives last, so fee and arrival disagree on it
+		cheapKey, _ := crypto.GenerateKey()
+		priceyKey, _ := crypto.GenerateKey()
+		cheap, pricey := crypto.PubkeyToAddress(cheapKey.PublicKey), crypto.PubkeyToAddress(priceyKey.PublicKey)
+		testAddBalance(pool, cheap, big.NewInt(10000000))
+		testAddBalance(pool, pricey, big.NewInt(10000000))
+
+		var txs types.Transactions
+		for i := uint64(0); i < 3; i++ {
+			txs = append(txs, pricedTransaction(i, 100000, big.NewInt(1), cheapKey))
+		}
+		for i := uint64(0); i < 3; i++ {
+			txs = append(txs, pricedTransaction(i, 100000, big.NewInt(2), priceyKey))
+		}
+		pool.addRemotesSync(txs)
+
+		want := map[common.Address]int{cheap: 2, pricey: 3}
+		if ordering == OrderByArrival {
+			want = map[common.Address]int{cheap: 3, pricey: 2}
+		}
+		for addr, n := range want {
+			if have := pool.pending[addr].Len(); have != n {
+				t.Errorf("%s: account %x pending mismatch: have %d, want %d", ordering, addr, have, n)
+			}
+		}
+		if err := validatePoolInternals(pool); err != nil {
+			t.Fatalf("%s: pool internal state corrupted: %v", ordering, err)
+		}
+		pool.Close()
+	}
+}
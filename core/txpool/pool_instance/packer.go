@@ -0,0 +1,312 @@
+package txpool_instance
+
+import (
+	"bytes"
+	"container/heap"
+	"math/big"
+
+	"execution/common"
+	"execution/types"
+	"execution/types/gadget"
+)
+
+// PackedBlock is an ordering of pending transactions together with a lane
+// assignment. Transactions in different lanes touch disjoint state and can be
+// executed in parallel; transactions within a lane must be executed in order.
+type PackedBlock struct {
+	Txs     types.Transactions // Transactions in block order
+	Lanes   []int              // Lane index of each transaction in Txs
+	LaneGas []uint64           // Gas limit accumulated by each lane
+}
+
+// Len returns the number of packed transactions.
+func (b *PackedBlock) Len() int {
+	return len(b.Txs)
+}
+
+// Lane returns the transactions assigned to the given lane, in block order.
+func (b *PackedBlock) Lane(lane int) types.Transactions {
+	var txs types.Transactions
+	for i, tx := range b.Txs {
+		if b.Lanes[i] == lane {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+// PackParallel selects transactions from the pending set for a block of at most
+// gasLimit gas and distributes them over the given number of parallel lanes.
+//
+// Transactions are picked across accounts in the order of the given ordering
+// policy at the given base fee, by price if nil, and in nonce order within an
+// account, exactly like a sequential block would be. Accounts whose next
+// transaction can't pay the base fee are skipped. Each picked transaction
+// is placed into the lane it conflicts with according to its StrictAccessList,
+// or into the least loaded lane if it conflicts with none. If it conflicts with
+// several lanes, those lanes are merged since their transactions can no longer
+// run independently.
+//
+// Besides the strict access list, a transaction always writes its sender (nonce
+// and balance) and its recipient (balance), so transactions of one account land
+// in the same lane.
+func PackParallel(pending map[common.Address][]*types.Transaction, policy OrderingPolicy, baseFee *big.Int, gasLimit uint64, lanes int) *PackedBlock {
+	if lanes < 1 {
+		lanes = 1
+	}
+	if policy == nil {
+		policy = feeOrdering{}
+	}
+	var (
+		block = &PackedBlock{LaneGas: make([]uint64, lanes)}
+		sets  = make([]*accessSet, lanes)
+		heads = txsByPolicy{policy: policy, baseFee: baseFee}
+		txs   = make(map[common.Address][]*types.Transaction, len(pending))
+	)
+	for i := range sets {
+		sets[i] = newAccessSet()
+	}
+	for addr, list := range pending {
+		if len(list) == 0 {
+			continue
+		}
+		heads.txs = append(heads.txs, list[0])
+		heads.ahead = append(heads.ahead, 0)
+		txs[addr] = list[1:]
+	}
+	heap.Init(&heads)
+
+	remaining := gasLimit
+	for heads.Len() > 0 {
+		tx := heads.txs[0]
+		if tx.GasLimit > remaining {
+			// Later nonces can't be included either, drop the whole account
+			heap.Pop(&heads)
+			continue
+		}
+		if baseFee != nil && tx.GasPrice.GasFeeCapIntCmp(baseFee) < 0 {
+			heap.Pop(&heads)
+			continue
+		}
+		access := txAccessSet(tx)
+
+		var conflicts []int
+		for lane, set := range sets {
+			if set.conflicts(access) {
+				conflicts = append(conflicts, lane)
+			}
+		}
+		var lane int
+		switch len(conflicts) {
+		case 0:
+			for i, gas := range block.LaneGas {
+				if gas < block.LaneGas[lane] {
+					lane = i
+				}
+			}
+		case 1:
+			lane = conflicts[0]
+		default:
+			lane = conflicts[0]
+			for _, other := range conflicts[1:] {
+				block.mergeLane(lane, other)
+				sets[lane].merge(sets[other])
+				sets[other] = newAccessSet()
+			}
+		}
+		sets[lane].merge(access)
+		block.Txs = append(block.Txs, tx)
+		block.Lanes = append(block.Lanes, lane)
+		block.LaneGas[lane] += tx.GasLimit
+		remaining -= tx.GasLimit
+
+		// Move on to the account's next transaction
+		if next := txs[tx.From]; len(next) > 0 {
+			heads.txs[0], txs[tx.From] = next[0], next[1:]
+			heads.ahead[0]++
+			heap.Fix(&heads, 0)
+		} else {
+			heap.Pop(&heads)
+		}
+	}
+	return block
+}
+
+// PendingParallel packs the currently processable transactions into a block with
+// PackParallel, ordered by the pool's ordering policy, and hands them out like
+// Pending does: the packed transactions are removed from the pool. Those left out
+// of the block, for gas or base fee, stay in the pool for later blocks.
+func (pool *LegacyPool) PendingParallel(enforceTips bool, baseFee *big.Int, gasLimit uint64, lanes int) *PackedBlock {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	block := PackParallel(pool.ready(enforceTips), pool.ordering, baseFee, gasLimit, lanes)
+
+	// Each account's transactions are packed in nonce order
+	packed := make(map[common.Address][]*types.Transaction)
+	for _, tx := range block.Txs {
+		packed[tx.From] = append(packed[tx.From], tx)
+	}
+	pool.take(packed)
+	return block
+}
+
+// mergeLane moves every transaction of lane src into lane dst.
+func (b *PackedBlock) mergeLane(dst, src int) {
+	for i, lane := range b.Lanes {
+		if lane == src {
+			b.Lanes[i] = dst
+		}
+	}
+	b.LaneGas[dst] += b.LaneGas[src]
+	b.LaneGas[src] = 0
+}
+
+// accessSet tracks the accounts and storage slots read and written by a group
+// of transactions.
+type accessSet struct {
+	reads  map[common.Address]*accessEntry
+	writes map[common.Address]*accessEntry
+}
+
+// accessEntry records the parts of a single account that were accessed. If
+// account is set, the account as a whole was accessed.
+type accessEntry struct {
+	account bool
+	slots   map[common.Hash]struct{}
+}
+
+func newAccessSet() *accessSet {
+	return &accessSet{
+		reads:  make(map[common.Address]*accessEntry),
+		writes: make(map[common.Address]*accessEntry),
+	}
+}
+
+// txAccessSet assembles the state accessed by a transaction from its strict
+// access list and its implicit sender and recipient accesses.
+func txAccessSet(tx *types.Transaction) *accessSet {
+	set := newAccessSet()
+	if tx.StrictAccessList != nil {
+		for _, tuple := range tx.StrictAccessList.Reads {
+			addTuple(set.reads, tuple)
+		}
+		for _, tuple := range tx.StrictAccessList.Writes {
+			addTuple(set.writes, tuple)
+		}
+	}
+	if (tx.From != common.Address{}) {
+		addTuple(set.writes, gadget.AccessTuple{Address: tx.From})
+	}
+	if (tx.To != common.Address{}) {
+		addTuple(set.writes, gadget.AccessTuple{Address: tx.To})
+	}
+	return set
+}
+
+func addTuple(entries map[common.Address]*accessEntry, tuple gadget.AccessTuple) {
+	entry := entries[tuple.Address]
+	if entry == nil {
+		entry = &accessEntry{slots: make(map[common.Hash]struct{})}
+		entries[tuple.Address] = entry
+	}
+	if len(tuple.StorageKeys) == 0 {
+		entry.account = true
+	}
+	for _, key := range tuple.StorageKeys {
+		entry.slots[key] = struct{}{}
+	}
+}
+
+// overlaps reports whether two access entries of the same account touch any
+// common state.
+func (e *accessEntry) overlaps(other *accessEntry) bool {
+	if e.account && (other.account || len(other.slots) > 0) {
+		return true
+	}
+	if other.account && len(e.slots) > 0 {
+		return true
+	}
+	for slot := range other.slots {
+		if _, ok := e.slots[slot]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts reports whether executing other concurrently with the accesses in
+// the set could produce a different result than executing them in sequence,
+// i.e. whether either side writes state the other side reads or writes.
+func (s *accessSet) conflicts(other *accessSet) bool {
+	for addr, w := range other.writes {
+		if r := s.reads[addr]; r != nil && r.overlaps(w) {
+			return true
+		}
+		if ww := s.writes[addr]; ww != nil && ww.overlaps(w) {
+			return true
+		}
+	}
+	for addr, r := range other.reads {
+		if w := s.writes[addr]; w != nil && w.overlaps(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge adds all accesses of other into the set.
+func (s *accessSet) merge(other *accessSet) {
+	mergeEntries(s.reads, other.reads)
+	mergeEntries(s.writes, other.writes)
+}
+
+func mergeEntries(dst, src map[common.Address]*accessEntry) {
+	for addr, entry := range src {
+		have := dst[addr]
+		if have == nil {
+			have = &accessEntry{slots: make(map[common.Hash]struct{}, len(entry.slots))}
+			dst[addr] = have
+		}
+		have.account = have.account || entry.account
+		for slot := range entry.slots {
+			have.slots[slot] = struct{}{}
+		}
+	}
+}
+
+// txsByPolicy is a heap of account head transactions ordered by an ordering
+// policy, with the sender address as a deterministic tie breaker.
+type txsByPolicy struct {
+	policy  OrderingPolicy
+	baseFee *big.Int
+	txs     []*types.Transaction
+	ahead   []uint64 // Number of transactions of the sender packed before each head
+}
+
+func (s *txsByPolicy) Len() int { return len(s.txs) }
+func (s *txsByPolicy) Less(i, j int) bool {
+	if cmp := s.policy.Cmp(s.txs[i], s.txs[j], s.ahead[i], s.ahead[j], s.baseFee); cmp != 0 {
+		return cmp > 0
+	}
+	return bytes.Compare(s.txs[i].From[:], s.txs[j].From[:]) < 0
+}
+func (s *txsByPolicy) Swap(i, j int) {
+	s.txs[i], s.txs[j] = s.txs[j], s.txs[i]
+	s.ahead[i], s.ahead[j] = s.ahead[j], s.ahead[i]
+}
+
+func (s *txsByPolicy) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*types.Transaction))
+	s.ahead = append(s.ahead, 0)
+}
+
+func (s *txsByPolicy) Pop() interface{} {
+	old := s.txs
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	s.txs = old[0 : n-1]
+	s.ahead = s.ahead[0 : n-1]
+	return x
+}
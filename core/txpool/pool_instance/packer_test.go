@@ -0,0 +1,177 @@
+package txpool_instance
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types"
+	"execution/types/gadget"
+)
+
+// Tests that independent senders are spread over the lanes by gas while
+// transactions sharing written state are kept in the same lane.
+func TestPackParallelLanes(t *testing.T) {
+	t.Parallel()
+
+	var (
+		keys    = make([]*packerAccount, 4)
+		pending = make(map[common.Address][]*types.Transaction)
+		shared  = common.HexToHash("0x01")
+		target  = common.HexToAddress("0xc0ffee")
+	)
+	for i := range keys {
+		keys[i] = newPackerKey(t)
+	}
+	// Two plain transfers and two contract calls writing the same slot
+	for i := 0; i < 2; i++ {
+		tx := pricedTransaction(0, 100000, big.NewInt(int64(10+i)), keys[i].key)
+		pending[keys[i].addr] = []*types.Transaction{tx}
+	}
+	for i := 2; i < 4; i++ {
+		tx := pricedTransaction(0, 100000, big.NewInt(int64(10+i)), keys[i].key)
+		tx.StrictAccessList = &gadget.AccessList{
+			Writes: []gadget.AccessTuple{{Address: target, StorageKeys: []common.Hash{shared}}},
+		}
+		pending[keys[i].addr] = []*types.Transaction{tx}
+	}
+	// All transfers go to the same recipient, so give them distinct ones
+	for i := 0; i < 4; i++ {
+		pending[keys[i].addr][0].To = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+	block := PackParallel(pending, nil, nil, 10000000, 3)
+	if block.Len() != 4 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 4)
+	}
+	// Transactions must come out by price
+	for i := 1; i < block.Len(); i++ {
+		if block.Txs[i-1].GasPrice.Price.Cmp(block.Txs[i].GasPrice.Price) < 0 {
+			t.Fatalf("transaction %d priced above its predecessor", i)
+		}
+	}
+	if block.Lanes[0] != block.Lanes[1] {
+		t.Errorf("conflicting transactions split across lanes %d and %d", block.Lanes[0], block.Lanes[1])
+	}
+	if block.Lanes[2] == block.Lanes[0] || block.Lanes[3] == block.Lanes[0] || block.Lanes[2] == block.Lanes[3] {
+		t.Errorf("independent transactions not spread over lanes: %v", block.Lanes)
+	}
+	var total uint64
+	for _, gas := range block.LaneGas {
+		total += gas
+	}
+	if total != 400000 {
+		t.Errorf("lane gas mismatch: have %d, want %d", total, 400000)
+	}
+}
+
+// Tests that nonce order is preserved, the block gas limit is honoured and that
+// a transaction bridging two lanes merges them.
+func TestPackParallelMergeAndLimit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a, b, c = newPackerKey(t), newPackerKey(t), newPackerKey(t)
+		pending = make(map[common.Address][]*types.Transaction)
+	)
+	for i := uint64(0); i < 3; i++ {
+		tx := pricedTransaction(i, 100000, big.NewInt(int64(30-i)), a.key)
+		tx.To = common.HexToAddress("0xaa")
+		pending[a.addr] = append(pending[a.addr], tx)
+	}
+	txb := pricedTransaction(0, 100000, big.NewInt(25), b.key)
+	txb.To = common.HexToAddress("0xbb")
+	pending[b.addr] = []*types.Transaction{txb}
+
+	// c reads both recipients, bridging the lanes of a and b
+	txc := pricedTransaction(0, 100000, big.NewInt(1), c.key)
+	txc.To = common.HexToAddress("0xcc")
+	txc.StrictAccessList = &gadget.AccessList{
+		Reads: []gadget.AccessTuple{{Address: common.HexToAddress("0xaa")}, {Address: common.HexToAddress("0xbb")}},
+	}
+	pending[c.addr] = []*types.Transaction{txc}
+
+	block := PackParallel(pending, nil, nil, 500000, 2)
+	if block.Len() != 5 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 5)
+	}
+	var last uint64
+	for i, tx := range block.Txs {
+		if tx.From == a.addr {
+			if tx.Nonce < last {
+				t.Fatalf("transaction %d out of nonce order", i)
+			}
+			last = tx.Nonce
+		}
+	}
+	for i, lane := range block.Lanes {
+		if lane != block.Lanes[0] {
+			t.Fatalf("transaction %d not merged into lane %d: %v", i, block.Lanes[0], block.Lanes)
+		}
+	}
+	// Shrinking the gas limit drops the cheapest transaction
+	if block := PackParallel(pending, nil, nil, 400000, 2); block.Len() != 4 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 4)
+	}
+}
+
+// Tests that packing a block out of the pool only takes out the packed
+// transactions, leaving the ones skipped for gas or base fee for later blocks.
+func TestPendingParallelKeepsUnpacked(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(10000000))
+	txs := []*types.Transaction{
+		transaction(0, 100000, key),
+		transaction(1, 100000, key),
+		transaction(2, 100000, key),
+	}
+	for i, err := range pool.addRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	// Nothing pays the base fee, nothing is taken out
+	if block := pool.PendingParallel(true, big.NewInt(1000), 10000000, 1); block.Len() != 0 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 0)
+	}
+	for i, tx := range txs {
+		if pool.Get(tx.TxHash) == nil {
+			t.Fatalf("tx %d: dropped by base fee skip", i)
+		}
+	}
+	// Only two transactions fit the gas limit, the third stays
+	block := pool.PendingParallel(true, nil, 250000, 1)
+	if block.Len() != 2 {
+		t.Fatalf("packed transaction count mismatch: have %d, want %d", block.Len(), 2)
+	}
+	for i, tx := range txs {
+		if have, want := pool.Get(tx.TxHash) != nil, i == 2; have != want {
+			t.Errorf("tx %d: pooled mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Errorf("pool stats mismatch: have %d pending %d queued, want 1 pending", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// packerAccount is a test account signing transactions for the packer tests.
+type packerAccount struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+func newPackerKey(t *testing.T) *packerAccount {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packerAccount{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+}
@@ -1,10 +1,11 @@
 package txpool_instance
 
 import (
+	"container/heap"
 	"execution/common"
 	"execution/core/state"
-	"execution/core/types"
 	"execution/params"
+	"execution/types"
 	"math"
 	"math/big"
 	"sort"
@@ -12,7 +13,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -33,6 +33,9 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Store      string        // Database of all pooled transactions to survive node restarts (disabled if empty)
+	Checkpoint time.Duration // Time interval to sync the pool contents into the store
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -42,6 +45,15 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	AccountRate     float64       // Remote transactions accepted per second from a single sender (unlimited if zero)
+	AccountBurst    uint64        // Remote transactions a single sender may submit at once
+	PeerRate        float64       // Transactions accepted per second from a single peer (unlimited if zero)
+	PeerBurst       uint64        // Transactions a single peer may relay at once
+	ReplaceInterval time.Duration // Minimum time between two replacements by a remote sender (unlimited if zero)
+
+	Ordering string // Policy ordering transactions across accounts (OrderByFee, OrderByArrival or OrderByFairShare)
+	Lanes    []Lane // Priority lanes of senders with reserved capacity, in order of precedence
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -49,6 +61,8 @@ var DefaultConfig = Config{
 	Journal:   "transactions.encoded",
 	Rejournal: time.Hour,
 
+	Checkpoint: time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -58,6 +72,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	Ordering: OrderByFee,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -68,6 +84,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Checkpoint < time.Second {
+		log.Warn("Sanitizing invalid txpool store checkpoint time", "provided", conf.Checkpoint, "updated", time.Second)
+		conf.Checkpoint = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultConfig.PriceLimit)
 		conf.PriceLimit = DefaultConfig.PriceLimit
@@ -96,6 +116,35 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.AccountRate < 0 {
+		log.Warn("Sanitizing invalid txpool account rate", "provided", conf.AccountRate, "updated", 0)
+		conf.AccountRate = 0
+	}
+	if conf.AccountRate > 0 && conf.AccountBurst < 1 {
+		log.Warn("Sanitizing invalid txpool account burst", "provided", conf.AccountBurst, "updated", 1)
+		conf.AccountBurst = 1
+	}
+	if conf.PeerRate < 0 {
+		log.Warn("Sanitizing invalid txpool peer rate", "provided", conf.PeerRate, "updated", 0)
+		conf.PeerRate = 0
+	}
+	if conf.PeerRate > 0 && conf.PeerBurst < 1 {
+		log.Warn("Sanitizing invalid txpool peer burst", "provided", conf.PeerBurst, "updated", 1)
+		conf.PeerBurst = 1
+	}
+	if conf.ReplaceInterval < 0 {
+		log.Warn("Sanitizing invalid txpool replace interval", "provided", conf.ReplaceInterval, "updated", 0)
+		conf.ReplaceInterval = 0
+	}
+	switch conf.Ordering {
+	case OrderByFee, OrderByArrival, OrderByFairShare:
+	default:
+		log.Warn("Sanitizing invalid txpool ordering", "provided", conf.Ordering, "updated", DefaultConfig.Ordering)
+		conf.Ordering = DefaultConfig.Ordering
+	}
+	if len(conf.Lanes) > 0 {
+		conf.Lanes = sanitizeLanes(conf.Lanes, conf.GlobalSlots)
+	}
 	return conf
 }
 
@@ -110,23 +159,31 @@ type LegacyPool struct {
 	config      Config
 	chainconfig *params.ChainConfig
 	chain       types.BlockChain
+	signer      types.Signer
 	gasTip      atomic.Pointer[big.Int]
 	txFeed      event.Feed
 	scope       event.SubscriptionScope
 	mu          sync.RWMutex
 
-	currentHead   atomic.Pointer[types.Header] // Current head of the blockchain
-	currentState  state.StateDB                // Current state in the blockchain head
-	pendingNonces *Noncer                      // Pending state tracking virtual nonces
+	currentHead  atomic.Pointer[types.Header] // Current head of the blockchain
+	currentState state.StateDB                // Current state in the blockchain head
+	pendingState *VirtualState                // Pending state tracking virtual nonces and balances
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *journal    // Journal of local transaction to back up to disk
+	store   *txStore    // Store of all pooled transactions to back up to disk
+
+	pending   map[common.Address]*List     // All currently processable transactions
+	queue     map[common.Address]*List     // Queued but non-processable transactions
+	beats     map[common.Address]time.Time // Last heartbeat from each known account
+	all       *Lookup                      // All transactions to allow lookups
+	priced    *PricedList                  // All transactions sorted by price
+	ordering  OrderingPolicy               // Policy ordering transactions across accounts
+	laneIndex map[common.Address]int       // Lane index of each lane sender
 
-	pending map[common.Address]*List     // All currently processable transactions
-	queue   map[common.Address]*List     // Queued but non-processable transactions
-	beats   map[common.Address]time.Time // Last heartbeat from each known account
-	all     *Lookup                      // All transactions to allow lookups
-	priced  *PricedList                  // All transactions sorted by price
+	accountLimiter *rateLimiter[common.Address] // Rate limiter of remote transactions per sender
+	peerLimiter    *rateLimiter[string]         // Rate limiter of transactions per source peer
+	replaced       map[common.Address]time.Time // Last replacement made by each remote sender
 
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
@@ -154,10 +211,14 @@ func New(config Config, chain types.BlockChain) *LegacyPool {
 		config:          config,
 		chain:           chain,
 		chainconfig:     chain.Config(),
+		signer:          types.MakeSigner(chain.Config()),
 		pending:         make(map[common.Address]*List),
 		queue:           make(map[common.Address]*List),
 		beats:           make(map[common.Address]time.Time),
 		all:             NewLookup(),
+		accountLimiter:  newRateLimiter[common.Address](config.AccountRate, config.AccountBurst),
+		peerLimiter:     newRateLimiter[string](config.PeerRate, config.PeerBurst),
+		replaced:        make(map[common.Address]time.Time),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -170,11 +231,36 @@ func New(config Config, chain types.BlockChain) *LegacyPool {
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
-	pool.priced = NewPricedList(pool.all)
+	// Remember arrivals for twice the pool capacity, so the order of transactions
+	// just handed out for block production is still known
+	pool.ordering = newOrderingPolicy(config.Ordering, int(2*(config.GlobalSlots+config.GlobalQueue)))
+
+	pool.laneIndex = make(map[common.Address]int)
+	for i, lane := range config.Lanes {
+		log.Info("Setting up transaction pool lane", "name", lane.Name, "senders", len(lane.Senders), "slots", lane.Slots, "minprice", lane.MinPrice)
+		for _, addr := range lane.Senders {
+			pool.laneIndex[addr] = i
+		}
+	}
+	pool.all.trackLanes(pool.laneIndex, len(config.Lanes))
+	// Lane transactions are never evicted, so lane precedence only matters for
+	// the order transactions are handed out in
+	pool.priced = NewPricedList(pool.all, pool.ordering, pool.ahead, pool.inLane)
+	if len(config.Lanes) > 0 {
+		pool.ordering = laneOrdering{OrderingPolicy: pool.ordering, lanes: pool.laneIndex, count: len(config.Lanes)}
+	}
 
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 	}
+	if config.Store != "" {
+		store, err := newTxStore(config.Store)
+		if err != nil {
+			log.Warn("Failed to open transaction pool store", "path", config.Store, "err", err)
+		} else {
+			pool.store = store
+		}
+	}
 	return pool
 }
 
@@ -230,6 +316,18 @@ func (pool *LegacyPool) Init(gasTip *big.Int, head *types.Header) error {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the pool store is enabled, restore the full pool contents from disk,
+	// re-validating them against the current head
+	if pool.store != nil {
+		if err := pool.store.load(pool.addLocals, pool.addRemotesSync); err != nil {
+			log.Warn("Failed to load transaction pool store", "err", err)
+		}
+		pool.mu.Lock()
+		if err := pool.store.checkpoint(pool.all); err != nil {
+			log.Warn("Failed to checkpoint transaction pool store", "err", err)
+		}
+		pool.mu.Unlock()
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -248,10 +346,16 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 	if tip.Cmp(old) > 0 {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(tip)
+		dropped := 0
 		for _, tx := range drop {
+			// Lane transactions are held to the lane's minimum instead
+			if pool.inLane(tx) {
+				continue
+			}
 			pool.removeTx(tx.TxHash, false)
+			dropped++
 		}
-		pool.priced.Removed(len(drop))
+		pool.priced.Removed(dropped)
 	}
 	log.Info("Legacy pool tip threshold updated", "tip", tip)
 }
@@ -262,7 +366,17 @@ func (pool *LegacyPool) Nonce(addr common.Address) uint64 {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
-	return pool.pendingNonces.Get(addr)
+	return pool.pendingState.GetNonce(addr)
+}
+
+// PendingState returns a copy of the pending state, the nonces and balances of
+// accounts with all transactions executable by the pool already applied on top,
+// e.g. to apply transactions speculatively while building a block.
+func (pool *LegacyPool) PendingState() *VirtualState {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.pendingState.Copy()
 }
 
 // Content retrieves the data content of the transaction pool, returning all the
@@ -306,25 +420,50 @@ func (pool *LegacyPool) Pending() map[common.Address][]*types.Transaction {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	pending := pool.ready(true)
+	pool.take(pending)
+	return pending
+}
+
+// ready returns the currently processable transactions, grouped by origin
+// account and sorted by nonce, without removing them. With enforceTips set,
+// an account's transactions below its minimum tip and all after are left out.
+// The pool lock must be held.
+func (pool *LegacyPool) ready(enforceTips bool) map[common.Address][]*types.Transaction {
 	pending := make(map[common.Address][]*types.Transaction, len(pool.pending))
 	for addr, list := range pool.pending {
-		txs := list.Ready(pool.pendingNonces.nonces[addr], pool.currentState.GetBalance(addr))
-		for i, tx := range txs {
-			if tx.GasPrice.Price.Cmp(pool.gasTip.Load()) < 0 {
-				txs = txs[:i]
-				break
+		txs := list.PeekReady(pool.pendingState.GetNonce(addr), pool.pendingState.GetFunds(addr))
+		if enforceTips {
+			for i, tx := range txs {
+				if tx.GasPrice.EffectiveGasTipIntCmp(pool.minTip(addr), pool.priced.urgent.baseFee) < 0 {
+					txs = txs[:i]
+					break
+				}
 			}
 		}
-
 		if len(txs) > 0 {
 			pending[addr] = txs
 		}
+	}
+	return pending
+}
 
-		for _, tx := range txs {
+// take removes handed out transactions, a nonce prefix of the pending list of
+// each account, from the pool. The pending state is left alone, it already
+// accounts for them until the block including them arrives. The pool lock must
+// be held.
+func (pool *LegacyPool) take(txs map[common.Address][]*types.Transaction) {
+	for addr, set := range txs {
+		list := pool.pending[addr]
+		list.Forward(set[len(set)-1].Nonce + 1)
+		if list.Empty() {
+			delete(pool.pending, addr)
+		}
+		for _, tx := range set {
+			// Out of the pending list, this only drops the lookup entry
 			pool.removeTx(tx.TxHash, false)
 		}
 	}
-	return pending
 }
 
 // Status returns the status (unknown/pending/queued) of a batch of transactions
@@ -380,10 +519,12 @@ func (pool *LegacyPool) loop() {
 		report  = time.NewTicker(statsReportInterval)
 		evict   = time.NewTicker(evictionInterval)
 		journal = time.NewTicker(pool.config.Rejournal)
+		store   = time.NewTicker(pool.config.Checkpoint)
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer store.Stop()
 
 	// Notify tests that the init phase is done
 	close(pool.initDoneCh)
@@ -422,8 +563,18 @@ func (pool *LegacyPool) loop() {
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			// Forget replacements old enough not to throttle anymore
+			for addr, last := range pool.replaced {
+				if time.Since(last) >= pool.config.ReplaceInterval {
+					delete(pool.replaced, addr)
+				}
+			}
 			pool.mu.Unlock()
 
+			now := time.Now()
+			accountBucketGauge.Update(int64(pool.accountLimiter.prune(now)))
+			peerBucketGauge.Update(int64(pool.peerLimiter.prune(now)))
+
 		// Handle local transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
@@ -433,6 +584,16 @@ func (pool *LegacyPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle pool store checkpoints
+		case <-store.C:
+			if pool.store != nil {
+				pool.mu.Lock()
+				if err := pool.store.checkpoint(pool.all); err != nil {
+					log.Warn("Failed to checkpoint transaction pool store", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -458,8 +619,9 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			pool.all.Remove(hash)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
-		// Drop all transactions that are too costly (low balance or out of gas)
-		drops, _ := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
+		// Drop all transactions that are too costly (low balance or out of gas). The
+		// queue only gets what's left to spend beyond the pending transactions
+		drops, _ := list.Filter(pool.pendingState.GetBalance(addr), gasLimit)
 		for _, tx := range drops {
 			hash := tx.TxHash
 			pool.all.Remove(hash)
@@ -468,7 +630,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
 		// Gather all executable transactions and promote them
-		readies := list.Ready(pool.pendingNonces.Get(addr), pool.currentState.GetBalance(addr))
+		readies := list.Ready(pool.pendingState.GetNonce(addr), pool.pendingState.GetBalance(addr))
 		for _, tx := range readies {
 			hash := tx.TxHash
 			if pool.promoteTx(addr, hash, tx) {
@@ -527,13 +689,14 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 	if old != nil {
 		pool.all.Remove(old.TxHash)
 		pool.priced.Removed(1)
+		pool.pendingState.Unapply(old)
 		pendingReplaceMeter.Mark(1)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
-	// Set the potentially new pending nonce and notify any subsystems of the new tx
-	pool.pendingNonces.Set(addr, tx.Nonce+1)
+	// Set the potentially new pending nonce and balances and notify any subsystems of the new tx
+	pool.pendingState.Apply(tx)
 
 	// Successful promotion, bump the heartbeat
 	pool.beats[addr] = time.Now()
@@ -563,7 +726,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
-		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
+		drops, invalids := list.Filter(pool.pendingState.GetFunds(addr), gasLimit)
 		for _, tx := range drops {
 			hash := tx.TxHash
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
@@ -602,94 +765,111 @@ func (pool *LegacyPool) demoteUnexecutables() {
 }
 
 // truncatePending removes transactions from the pending queue if the pool is above the
-// pending limit. The algorithm tries to reduce transaction counts by an approximately
-// equal number for all for accounts with many pending transactions.
+// pending limit. Lanes are limited to their reserved slots and everyone else to
+// the rest of the pending limit.
 func (pool *LegacyPool) truncatePending() {
+	groups := make([][]common.Address, len(pool.config.Lanes)+1)
+	for addr := range pool.pending {
+		lane, ok := pool.laneOf(addr)
+		if !ok {
+			lane = len(pool.config.Lanes)
+		}
+		groups[lane] = append(groups[lane], addr)
+	}
+	limit := pool.config.GlobalSlots
+	for i, lane := range pool.config.Lanes {
+		pool.truncateAccounts(groups[i], lane.Slots)
+		limit -= lane.Slots
+	}
+	pool.truncateAccounts(groups[len(pool.config.Lanes)], limit)
+}
+
+// truncateAccounts removes pending transactions of the given accounts if they
+// are above the limit together. The algorithm tries to reduce transaction counts
+// by an approximately equal number for all for accounts with many pending
+// transactions, cutting the longest ones first. Among equally long ones, the
+// account whose last transaction is the least preferred by the ordering policy
+// is cut first.
+func (pool *LegacyPool) truncateAccounts(addrs []common.Address, limit uint64) {
 	pending := uint64(0)
-	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+	for _, addr := range addrs {
+		pending += uint64(pool.pending[addr].Len())
 	}
-	if pending <= pool.config.GlobalSlots {
+	if pending <= limit {
 		return
 	}
 
 	pendingBeforeCap := pending
 	// Assemble a spam order to penalize large transactors first
-	spammers := prque.New[int64, common.Address](nil)
-	for addr, list := range pool.pending {
+	spammers := &offenderHeap{pool: pool}
+	for _, addr := range addrs {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
-			spammers.Push(addr, int64(list.Len()))
-		}
-	}
-	// Gradually drop transactions from offenders
-	offenders := []common.Address{}
-	for pending > pool.config.GlobalSlots && !spammers.Empty() {
-		// Retrieve the next offender if not local address
-		offender, _ := spammers.Pop() // pop the greatest priority one, which is the one with the most pending txs
-		offenders = append(offenders, offender)
-
-		// Equalize balances until all the same or below threshold
-		if len(offenders) > 1 {
-			// Calculate the equalization threshold for all current offenders
-			threshold := pool.pending[offender].Len()
-
-			// Iteratively reduce all offenders until below limit or threshold reached
-			// pending > limited pending slots && last offender's pending length > threshold (initially this offender's pending length)
-			for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
-				// for every already popped offender, reduce its pending txs by 1
-				for i := 0; i < len(offenders)-1; i++ {
-					list := pool.pending[offenders[i]]
-
-					caps := list.Cap(list.Len() - 1) // means a kind of pop
-					for _, tx := range caps {
-						// Drop the transaction from the global pools too
-						hash := tx.TxHash
-						pool.all.Remove(hash)
-
-						// Update the account nonce to the dropped transaction
-						pool.pendingNonces.SetIfLower(offenders[i], tx.Nonce)
-						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
-					}
-					pool.priced.Removed(len(caps))
-					pendingGauge.Dec(int64(len(caps)))
-					if pool.locals.contains(offenders[i]) {
-						localGauge.Dec(int64(len(caps)))
-					}
-					pending--
-				}
-			}
+		if !pool.locals.contains(addr) && uint64(pool.pending[addr].Len()) > pool.config.AccountSlots {
+			spammers.addrs = append(spammers.addrs, addr)
 		}
 	}
+	heap.Init(spammers)
 
-	// If still above threshold, reduce to limit or min allowance
-	if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-		for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
-			for _, addr := range offenders {
-				list := pool.pending[addr]
+	// Gradually drop transactions from offenders until below the limit or every
+	// offender is down to its minimum allowance
+	for pending > limit && spammers.Len() > 0 {
+		offender := spammers.addrs[0]
+		list := pool.pending[offender]
 
-				caps := list.Cap(list.Len() - 1)
-				for _, tx := range caps {
-					// Drop the transaction from the global pools too
-					hash := tx.TxHash
-					pool.all.Remove(hash)
+		caps := list.Cap(list.Len() - 1) // means a kind of pop
+		for _, tx := range caps {
+			// Drop the transaction from the global pools too
+			hash := tx.TxHash
+			pool.all.Remove(hash)
 
-					// Update the account nonce to the dropped transaction
-					pool.pendingNonces.SetIfLower(addr, tx.Nonce)
-					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
-				}
-				pool.priced.Removed(len(caps))
-				pendingGauge.Dec(int64(len(caps)))
-				if pool.locals.contains(addr) {
-					localGauge.Dec(int64(len(caps)))
-				}
-				pending--
-			}
+			// Update the account nonce and balances to the dropped transaction
+			pool.pendingState.Unapply(tx)
+			log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+		}
+		pool.priced.Removed(len(caps))
+		pendingGauge.Dec(int64(len(caps)))
+		pending -= uint64(len(caps))
+
+		if uint64(list.Len()) > pool.config.AccountSlots {
+			heap.Fix(spammers, 0)
+		} else {
+			heap.Pop(spammers)
 		}
 	}
 	pendingRateLimitMeter.Mark(int64(pendingBeforeCap - pending))
 }
 
+// offenderHeap is a heap of accounts over their pending allowance, ordered by
+// which one truncateAccounts cuts first.
+type offenderHeap struct {
+	pool  *LegacyPool
+	addrs []common.Address
+}
+
+func (h *offenderHeap) Len() int      { return len(h.addrs) }
+func (h *offenderHeap) Swap(i, j int) { h.addrs[i], h.addrs[j] = h.addrs[j], h.addrs[i] }
+
+func (h *offenderHeap) Less(i, j int) bool {
+	a, b := h.pool.pending[h.addrs[i]], h.pool.pending[h.addrs[j]]
+	if a.Len() != b.Len() {
+		return a.Len() > b.Len()
+	}
+	lastA, lastB := a.LastElement(), b.LastElement()
+	return h.pool.ordering.Cmp(lastA, lastB, h.pool.ahead(lastA), h.pool.ahead(lastB), h.pool.priced.urgent.baseFee) < 0
+}
+
+func (h *offenderHeap) Push(x interface{}) {
+	h.addrs = append(h.addrs, x.(common.Address))
+}
+
+func (h *offenderHeap) Pop() interface{} {
+	old := h.addrs
+	n := len(old)
+	x := old[n-1]
+	h.addrs = old[0 : n-1]
+	return x
+}
+
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
 type addressByHeartbeat struct {
 	address   common.Address
@@ -769,7 +949,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
-			events[addr].Forward(pool.pendingNonces.Get(addr))
+			events[addr].Forward(pool.pendingState.GetNonce(addr))
 			if events[addr].Len() == 0 {
 				delete(events, addr)
 			}
@@ -789,20 +969,21 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	if reset != nil {
 		pool.demoteUnexecutables()
 		if reset.newHead != nil {
-			// if pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
-			// 	pendingBaseFee := misc.CalcBaseFee(pool.chainconfig, reset.newHead)
-			// 	pool.priced.SetBaseFee(pendingBaseFee)
-			// } else {
-			pool.priced.Reheap()
-			// }
+			if baseFee := reset.newHead.BaseFee(); baseFee != nil {
+				pendingBaseFee := params.CalcBaseFee(pool.chainconfig, baseFee, reset.newHead.GasUsed(), reset.newHead.GasLimit())
+				pool.priced.SetBaseFee(pendingBaseFee)
+			} else {
+				pool.priced.Reheap()
+			}
 		}
-		// Update all accounts to the latest known pending nonce
-		nonces := make(map[common.Address]uint64, len(pool.pending))
-		for addr, list := range pool.pending {
-			highestPending := list.LastElement()
-			nonces[addr] = highestPending.Nonce + 1
+		// Rebuild the pending state from the pending transactions left, updating
+		// all accounts to the latest known pending nonce and balance
+		pool.pendingState.Clear()
+		for _, list := range pool.pending {
+			for _, tx := range list.Flatten() {
+				pool.pendingState.Apply(tx)
+			}
 		}
-		pool.pendingNonces.SetAll(nonces)
 	}
 	// Ensure pool.queue and pool.pending sizes stay within the configured limits.
 	pool.truncatePending()
@@ -950,14 +1131,31 @@ func (pool *LegacyPool) stats() (int, int) {
 // of the transaction pool is valid with regard to the chain state.
 func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var (
+		reinject types.Transactions
+		forked   []blockRef // Discarded blocks to reinject through the chain index
+	)
+	index, indexed := pool.chain.(ChainIndex)
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash() {
-		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number().Uint64()
 		newNum := newHead.Number().Uint64()
 
-		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
+		if indexed {
+			// The index finds the common ancestor and the transactions kept by the
+			// new chain without loading it, so reorgs of any depth can be handled
+			var rooted bool
+			forked, rooted = pool.forkedBlocks(index, oldHead)
+			switch {
+			case !rooted && len(forked) == 0:
+				// The old head was discarded, e.g. by a setHead, along with its transactions
+				log.Debug("Skipping transaction reset with missing oldhead",
+					"old", oldHead.Hash(), "oldnum", oldNum, "new", newHead.Hash(), "newnum", newNum)
+			case !rooted:
+				log.Error("Unrooted old chain seen by tx pool", "block", oldHead.Number(), "hash", oldHead.Hash(), "found", len(forked))
+			}
+		} else if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
+			// If the reorg is too deep, avoid doing it (will happen during fast sync)
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
@@ -986,7 +1184,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 					// the firing of newhead-event and _now_: most likely a
 					// reorg caused by sync-reversion or explicit sethead back to an
 					// earlier block.
-					log.Warn("New head missing in txpool reset", "number", newHead.Number, "hash", newHead.Hash())
+					log.Warn("New head missing in txpool reset", "number", newHead.Number(), "hash", newHead.Hash())
 					return
 				}
 				var discarded, included types.Transactions
@@ -994,7 +1192,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 				for rem.NumberU64() > add.NumberU64() {
 					discarded = append(discarded, rem.Transactions()...)
 					if rem = pool.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
-						log.Error("Unrooted old chain seen by tx pool", "block", oldHead.Number, "hash", oldHead.Hash())
+						log.Error("Unrooted old chain seen by tx pool", "block", oldHead.Number(), "hash", oldHead.Hash())
 						return
 					}
 				}
@@ -1003,7 +1201,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 				for add.NumberU64() > rem.NumberU64() {
 					included = append(included, add.Transactions()...)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
-						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
+						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number(), "hash", newHead.Hash())
 						return
 					}
 				}
@@ -1015,12 +1213,12 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 				for rem.Hash() != add.Hash() {
 					discarded = append(discarded, rem.Transactions()...)
 					if rem = pool.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
-						log.Error("Unrooted old chain seen by tx pool", "block", oldHead.Number, "hash", oldHead.Hash())
+						log.Error("Unrooted old chain seen by tx pool", "block", oldHead.Number(), "hash", oldHead.Hash())
 						return
 					}
 					included = append(included, add.Transactions()...)
 					if add = pool.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
-						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number, "hash", newHead.Hash())
+						log.Error("Unrooted new chain seen by tx pool", "block", newHead.Number(), "hash", newHead.Hash())
 						return
 					}
 				}
@@ -1039,25 +1237,88 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	}
 	pool.currentHead.Store(newHead)
 	pool.currentState = statedb
-	pool.pendingNonces = NewNoncer(statedb)
+	pool.pendingState = NewVirtualState(&statedb)
 
 	// Inject any transactions discarded due to reorgs
-	log.Debug("Reinjecting stale transactions", "count", len(reinject))
-	// Ethereum needs to recover the from or some other fields
-	// I am not sure if we need that
-	// core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	log.Debug("Reinjecting stale transactions", "count", len(reinject), "blocks", len(forked))
+	reinjected, lost := pool.reinject(reinject)
+	if len(forked) > 0 {
+		r, l := pool.reinjectBlocks(index, forked, newHead.Number().Uint64())
+		reinjected, lost = reinjected+r, lost+l
+	}
+	if reinjected > 0 || lost > 0 {
+		log.Info("Reinjected transactions of reorged blocks", "reinjected", reinjected, "lost", lost)
+	}
+
+	// Drop everything which can't make it into the next block anymore
+	pool.dropExpired(newHead)
+}
+
+// dropExpired removes all transactions past their deadline for the block after
+// the given head, demoting any pending transactions after them. Timestamp
+// deadlines are checked against the wall clock. The pool lock must be held.
+func (pool *LegacyPool) dropExpired(head *types.Header) {
+	var (
+		number = head.Number().Uint64() + 1
+		now    = uint64(time.Now().Unix())
+		drop   []common.Hash
+	)
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if tx.CheckDeadline(number, now) != nil {
+			drop = append(drop, hash)
+		}
+		return true
+	}, true, true)
+
+	for _, hash := range drop {
+		log.Trace("Removed expired transaction", "hash", hash)
+		pool.removeTx(hash, true)
+	}
+	expiredMeter.Mark(int64(len(drop)))
 }
 
 // Add enqueues a batch of transactions into the pool if they are valid. Depending
-// on the local flag, full pricing contraints will or will not be applied.
+// on the local flag, full pricing contraints will or will not be applied. Remote
+// transactions are subject to the per-sender throttling.
 //
 // If sync is set, the method will block until all internal maintenance related
 // to the add is finished. Only use this during tests for determinism!
 func (pool *LegacyPool) Add(txs types.Transactions, local bool, sync bool) []error {
 	unwrapped := make([]*types.Transaction, len(txs))
 	copy(unwrapped, txs)
-	return pool.addTxs(unwrapped, local, sync)
+	return pool.addTxs(unwrapped, local, !local, sync)
+}
+
+// AddFromPeer enqueues a batch of remote transactions relayed by the given peer.
+// On top of the checks done by Add, the transactions are subject to the per-peer
+// rate limit, with ErrRateLimited reported for the ones exceeding it.
+func (pool *LegacyPool) AddFromPeer(txs types.Transactions, peer string, sync bool) []error {
+	var (
+		errs = make([]error, len(txs))
+		news = make([]*types.Transaction, 0, len(txs))
+		now  = time.Now()
+	)
+	for i, tx := range txs {
+		// Known transactions are cheap to reject, don't charge the peer for them
+		if pool.all.Get(tx.TxHash) == nil && !pool.peerLimiter.allow(peer, now) {
+			errs[i] = ErrRateLimited
+			peerRateLimitMeter.Mark(1)
+			continue
+		}
+		news = append(news, tx)
+	}
+	if len(news) == 0 {
+		return errs
+	}
+	var nilSlot = 0
+	for _, err := range pool.addTxs(news, false, true, sync) {
+		for errs[nilSlot] != nil {
+			nilSlot++
+		}
+		errs[nilSlot] = err
+		nilSlot++
+	}
+	return errs
 }
 
 // addLocals enqueues a batch of transactions into the pool if they are valid, marking the
@@ -1066,7 +1327,7 @@ func (pool *LegacyPool) Add(txs types.Transactions, local bool, sync bool) []err
 // This method is used to add transactions from the RPC API and performs synchronous pool
 // reorganization and event propagation.
 func (pool *LegacyPool) addLocals(txs types.Transactions) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals, true)
+	return pool.addTxs(txs, !pool.config.NoLocals, false, true)
 }
 
 // addLocal enqueues a single local transaction into the pool if it is valid. This is
@@ -1082,7 +1343,7 @@ func (pool *LegacyPool) addLocal(tx *types.Transaction) error {
 // This method is used to add transactions from the p2p network and does not wait for pool
 // reorganization and internal event propagation.
 func (pool *LegacyPool) addRemotes(txs types.Transactions) []error {
-	return pool.addTxs(txs, false, false)
+	return pool.addTxs(txs, false, false, false)
 }
 
 // addRemote enqueues a single transaction into the pool if it is valid. This is a convenience
@@ -1094,16 +1355,18 @@ func (pool *LegacyPool) addRemote(tx *types.Transaction) error {
 
 // addRemotesSync is like addRemotes, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemotesSync(txs types.Transactions) []error {
-	return pool.addTxs(txs, false, true)
+	return pool.addTxs(txs, false, false, true)
 }
 
 // This is like addRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
-	return pool.addTxs([]*types.Transaction{tx}, false, true)[0]
+	return pool.addTxs([]*types.Transaction{tx}, false, false, true)[0]
 }
 
-// addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *LegacyPool) addTxs(txs types.Transactions, local, sync bool) []error {
+// addTxs attempts to queue a batch of transactions if they are valid. Only
+// transactions arriving from the network are throttled, those the pool adds back
+// on its own (reorgs, the store) must not be dropped by the rate limits.
+func (pool *LegacyPool) addTxs(txs types.Transactions, local, throttle, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
 		errs = make([]error, len(txs))
@@ -1116,6 +1379,17 @@ func (pool *LegacyPool) addTxs(txs types.Transactions, local, sync bool) []error
 			knownTxMeter.Mark(1)
 			continue
 		}
+		news = append(news, tx)
+	}
+	// Recover the senders of the unknown transactions concurrently, so that the
+	// validation below only hits the sender cache
+	<-types.SenderCacher.Recover(pool.signer, news)
+
+	news = news[:0]
+	for i, tx := range txs {
+		if errs[i] != nil {
+			continue
+		}
 		// Exclude transactions with basic errors, e.g invalid signatures and
 		// insufficient intrinsic gas as soon as possible and cache senders
 		// in transactions before obtaining lock
@@ -1133,7 +1407,7 @@ func (pool *LegacyPool) addTxs(txs types.Transactions, local, sync bool) []error
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, throttle)
 	pool.mu.Unlock()
 
 	var nilSlot = 0
@@ -1165,11 +1439,11 @@ func (pool *LegacyPool) requestPromoteExecutables(set *accountSet) chan struct{}
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid.
 // The transaction pool lock must be held.
-func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local, throttle bool) ([]error, *accountSet) {
 	dirty := newAccountSet()
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
-		replaced, err := pool.add(tx, local)
+		replaced, err := pool.add(tx, local, throttle)
 		errs[i] = err
 		if err == nil && !replaced {
 			dirty.addTx(tx)
@@ -1185,8 +1459,9 @@ func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]er
 // and does not require the pool mutex to be held.
 func (pool *LegacyPool) validateTxBasics(tx *types.Transaction, local bool) error {
 	opts := &ValidationOptions{
+		Signer:  pool.signer,
 		MaxSize: txMaxSize,
-		MinTip:  pool.gasTip.Load(),
+		MinTip:  pool.minTip(tx.From),
 	}
 	if local {
 		opts.MinTip = new(big.Int)
@@ -1202,6 +1477,7 @@ func (pool *LegacyPool) validateTxBasics(tx *types.Transaction, local bool) erro
 func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 	opts := &ValidationOptionsWithState{
 		State: pool.currentState,
+		Funds: pool.pendingState.GetFunds,
 
 		FirstNonceGap: nil, // Pool allows arbitrary arrival order, don't invalidate nonce gaps
 		ExistingExpenditure: func(addr common.Address, nonce uint64) *big.Int {
@@ -1233,7 +1509,10 @@ func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 // If a newly added transaction is marked as local, its sending account will be
 // be added to the allowlist, preventing any associated transaction from being dropped
 // out of the pool due to pricing constraints.
-func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, err error) {
+//
+// Remote transactions arriving from the network are throttled if the throttle
+// flag is set.
+func (pool *LegacyPool) add(tx *types.Transaction, local, throttle bool) (replaced bool, err error) {
 	// If the transaction is already known, discard it
 	hash := tx.TxHash
 	if pool.all.Get(hash) != nil {
@@ -1257,11 +1536,30 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	from := tx.From
 	// from, err := tx.TxPreface().Validation().GetFrom(tx.TxPreface().TxHash())
 
+	// Throttle remote senders flooding or churning the pool
+	if throttle && !isLocal {
+		if err := pool.throttle(from, tx, time.Now()); err != nil {
+			log.Trace("Discarding rate limited transaction", "hash", hash, "from", from, "err", err)
+			return false, err
+		}
+	}
+
+	// Slots reserved for lanes but unused are off limits for everyone else, while
+	// lane transactions within their reservation are admitted like local ones
+	capacity := pool.config.GlobalSlots + pool.config.GlobalQueue
+	reserved := false
+	if lane, ok := pool.laneOf(from); ok {
+		reserved = uint64(pool.laneSlots(lane)+numSlots(tx)) <= pool.config.Lanes[lane].Slots
+	} else if unused := pool.unusedReservations(); unused < capacity {
+		capacity -= unused
+	} else {
+		capacity = 0
+	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if uint64(pool.all.Slots()+numSlots(tx)) > capacity {
 
 		// If the new transaction is underpriced, don't accept it
-		if !isLocal && pool.priced.Underpriced(tx) {
+		if !isLocal && !reserved && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasPrice", tx.GasPrice)
 			underpricedTxMeter.Mark(1)
 			return false, ErrUnderpriced
@@ -1277,19 +1575,20 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		}
 
 		// New transaction is better than our worse ones, make room for it.
-		// If it's a local transaction, forcibly discard all available transactions.
+		// If it's a local transaction or one within its lane's reservation, forcibly
+		// discard all available transactions.
 		// Otherwise if we can't make enough room for new one, abort the operation.
-		drop, success := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
+		drop, success := pool.priced.Discard(pool.all.Slots()-int(capacity)+numSlots(tx), isLocal || reserved)
 
 		// Special case, we still can't make the room for the new remote one.
-		if !isLocal && !success {
+		if !isLocal && !reserved && !success {
 			log.Trace("Discarding overflown transaction", "hash", hash)
 			overflowedTxMeter.Mark(1)
 			return false, ErrTxPoolOverflow
 		}
 
 		// If the new transaction is a future transaction it should never churn pending transactions
-		if !isLocal && pool.isGapped(from, tx) {
+		if !isLocal && !reserved && pool.isGapped(from, tx) {
 			var replacesPending bool
 			for _, dropTx := range drop {
 				dropSender := dropTx.From
@@ -1330,9 +1629,11 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.TxHash)
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.markReplaced(from, isLocal || !throttle)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		pool.arrived(tx)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from)
@@ -1346,6 +1647,9 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	if err != nil {
 		return false, err
 	}
+	if replaced {
+		pool.markReplaced(from, isLocal || !throttle)
+	}
 	// Mark local addresses and journal local transactions
 	if local && !pool.locals.contains(from) {
 		log.Info("Setting new local account", "address", from)
@@ -1361,6 +1665,54 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	return replaced, nil
 }
 
+// throttle checks a remote transaction against the replacement interval and the
+// per-sender rate limit. A token is only spent if the interval check passes.
+func (pool *LegacyPool) throttle(from common.Address, tx *types.Transaction, now time.Time) error {
+	if pool.config.ReplaceInterval > 0 {
+		replacing := (pool.pending[from] != nil && pool.pending[from].Contains(tx.Nonce)) ||
+			(pool.queue[from] != nil && pool.queue[from].Contains(tx.Nonce))
+		if last, ok := pool.replaced[from]; ok && replacing && now.Sub(last) < pool.config.ReplaceInterval {
+			replaceRateLimitMeter.Mark(1)
+			return ErrReplaceTooSoon
+		}
+	}
+	if !pool.accountLimiter.allow(from, now) {
+		accountRateLimitMeter.Mark(1)
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// markReplaced records a replacement made by a remote sender to throttle the
+// next ones.
+func (pool *LegacyPool) markReplaced(from common.Address, local bool) {
+	if !local && pool.config.ReplaceInterval > 0 {
+		pool.replaced[from] = time.Now()
+	}
+}
+
+// arrived notifies the ordering policy of a transaction entering the pool, if
+// the policy cares about arrivals.
+func (pool *LegacyPool) arrived(tx *types.Transaction) {
+	if tracker, ok := pool.ordering.(arrivalTracker); ok {
+		tracker.arrived(tx)
+	}
+}
+
+// ahead returns the number of transactions of the sender which have to execute
+// before the given one. The pool lock must be held.
+func (pool *LegacyPool) ahead(tx *types.Transaction) uint64 {
+	if nonce := pool.currentState.GetNonce(tx.From); tx.Nonce > nonce {
+		return tx.Nonce - nonce
+	}
+	return 0
+}
+
+// Ordering returns the policy the pool orders transactions across accounts by.
+func (pool *LegacyPool) Ordering() OrderingPolicy {
+	return pool.ordering
+}
+
 // Close terminates the transaction pool.
 func (pool *LegacyPool) Close() error {
 	// Unsubscribe all subscriptions registered from txpool
@@ -1373,6 +1725,14 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.store != nil {
+		pool.mu.Lock()
+		if err := pool.store.checkpoint(pool.all); err != nil {
+			log.Warn("Failed to checkpoint transaction pool store", "err", err)
+		}
+		pool.mu.Unlock()
+		pool.store.close()
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -1389,7 +1749,7 @@ func (pool *LegacyPool) isGapped(from common.Address, tx *types.Transaction) boo
 	// or matches the next pending nonce which can be promoted as an executable
 	// transaction afterwards. Note, the tx staleness is already checked in
 	// 'validateTx' function previously.
-	next := pool.pendingNonces.Get(from)
+	next := pool.pendingState.GetNonce(from)
 	if tx.Nonce <= next {
 		return false
 	}
@@ -1439,6 +1799,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 	if addAll {
 		pool.all.Add(tx, local)
 		pool.priced.Put(tx, local)
+		pool.arrived(tx)
 	}
 	// If we never record the heartbeat, do it right now.
 	if _, exist := pool.beats[from]; !exist {
@@ -1490,8 +1851,11 @@ func (pool *LegacyPool) removeTx(hash common.Hash, outofbound bool) int {
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(tx.TxHash, tx, false, false)
 			}
-			// Update the account nonce if needed
-			pool.pendingNonces.SetIfLower(addr, tx.Nonce)
+			// Update the account nonce and balances if needed
+			pool.pendingState.Unapply(tx)
+			for _, tx := range invalids {
+				pool.pendingState.Unapply(tx)
+			}
 			// Reduce the pending counter
 			pendingGauge.Dec(int64(1 + len(invalids)))
 			return 1 + len(invalids)
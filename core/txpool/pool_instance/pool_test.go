@@ -7,10 +7,10 @@ import (
 	"execution/common"
 	"execution/core/rawdb"
 	"execution/core/state"
-	"execution/core/types"
-	"execution/core/types/gadget"
 	"execution/ethdb"
 	"execution/params"
+	"execution/types"
+	"execution/types/gadget"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -43,6 +43,10 @@ func newStateEnv() *stateEnv {
 	return &stateEnv{currentDB: db1, historyDB: db2, state: *sdb}
 }
 
+// testSigner signs test transactions for the chain of the test pools, which has
+// no chain config.
+var testSigner = types.MakeSigner(nil)
+
 func init() {
 	testTxPoolConfig = DefaultConfig
 	testTxPoolConfig.Journal = ""
@@ -71,7 +75,11 @@ func (bc *EasyBlockChain) Config() *params.ChainConfig {
 }
 
 func (bc *EasyBlockChain) CurrentBlock() *types.Header {
-	return types.NewHeader(common.Hash{}, common.Hash{}, new(big.Int), bc.gasLimit.Load())
+	return types.NewHeader(common.Hash{}, new(big.Int), bc.gasLimit.Load())
+}
+
+func (bc *EasyBlockChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return bc.CurrentBlock()
 }
 
 func (bc *EasyBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
@@ -94,7 +102,15 @@ func pricedTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ec
 	gp := gadget.NewGasPrice(gasprice)
 	to := common.Address{}
 	to.SetBytes([]byte("to"))
-	tx := types.NewNormalTransaction(nonce, to, big.NewInt(100), gaslimit, gp, nil, key)
+	tx := types.NewNormalTransaction(nonce, to, big.NewInt(100), gaslimit, gp, nil, testSigner, key)
+	return tx
+}
+
+func dynamicFeeTransaction(nonce uint64, gaslimit uint64, gasFee *big.Int, tip *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	gp := gadget.NewDynamicGasPrice(gasFee, tip)
+	to := common.Address{}
+	to.SetBytes([]byte("to"))
+	tx := types.NewNormalTransaction(nonce, to, big.NewInt(100), gaslimit, gp, nil, testSigner, key)
 	return tx
 }
 
@@ -104,7 +120,7 @@ func pricedDataTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key
 	gp := gadget.NewGasPrice(gasprice)
 	to := common.Address{}
 	to.SetBytes([]byte("to"))
-	tx := types.NewNormalTransaction(nonce, to, big.NewInt(100), gaslimit, gp, data, key)
+	tx := types.NewNormalTransaction(nonce, to, big.NewInt(100), gaslimit, gp, data, testSigner, key)
 	return tx
 }
 
@@ -137,10 +153,33 @@ func validatePoolInternals(pool *LegacyPool) error {
 		return fmt.Errorf("total transaction count %d != %d pending + %d queued", total, pending, queued)
 	}
 	pool.priced.Reheap()
-	priced, remote := pool.priced.urgent.Len()+pool.priced.floating.Len(), pool.all.RemoteCount()
+	priced, remote := pool.priced.urgent.Len()+pool.priced.floating.Len(), 0
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if !pool.priced.exempt(tx) {
+			remote++
+		}
+		return true
+	}, false, true)
 	if priced != remote {
 		return fmt.Errorf("total priced transaction count %d != %d", priced, remote)
 	}
+	// Ensure the lane slot counters match the lane transactions in the pool
+	for i, lane := range pool.config.Lanes {
+		slots := 0
+		for _, addr := range lane.Senders {
+			for _, list := range []*List{pool.pending[addr], pool.queue[addr]} {
+				if list == nil {
+					continue
+				}
+				for _, tx := range list.Flatten() {
+					slots += numSlots(tx)
+				}
+			}
+		}
+		if have := pool.laneSlots(i); have != slots {
+			return fmt.Errorf("lane %s slot count %d != %d", lane.Name, have, slots)
+		}
+	}
 	// Ensure the next nonce to assign is the correct one
 	for addr, txs := range pool.pending {
 		// Find the last transaction
@@ -150,7 +189,7 @@ func validatePoolInternals(pool *LegacyPool) error {
 				last = nonce
 			}
 		}
-		if nonce := pool.pendingNonces.Get(addr); nonce != last+1 {
+		if nonce := pool.pendingState.GetNonce(addr); nonce != last+1 {
 			return fmt.Errorf("pending nonce mismatch: have %v, want %v", nonce, last+1)
 		}
 		if txs.txs.tree.root.sum.Cmp(big.NewInt(0)) < 0 {
@@ -189,7 +228,7 @@ func validateEvents(events chan NewTxsEvent, count int) error {
 }
 
 func deriveSender(tx *types.Transaction) (common.Address, error) {
-	return tx.Validation.GetFrom(tx.TxHash)
+	return tx.Validation.GetFrom(tx.TxHash, testSigner.ChainID())
 }
 
 type testChain struct {
@@ -261,6 +300,10 @@ func testAddBalance(pool *LegacyPool, addr common.Address, amount *big.Int) {
 	pool.mu.Lock()
 	pool.currentState.AddBalance(addr, amount)
 	pool.mu.Unlock()
+
+	// The pending state reads a copy of the state taken at the last reset, reset
+	// the pool to pick the funds up
+	<-pool.requestReset(nil, nil)
 }
 
 func testSetNonce(pool *LegacyPool, addr common.Address, nonce uint64) {
@@ -308,6 +351,151 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that transactions missing their amounts or gas price don't crash the
+// pool: a missing value counts as zero, a missing gas price is refused.
+func TestMissingAmounts(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	tx := types.NewNormalTransaction(0, common.Address{0x01}, nil, 100000, gadget.NewGasPrice(big.NewInt(1)), nil, testSigner, key)
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add transaction without value: %v", err)
+	}
+	if cost := tx.Cost(); cost.Cmp(big.NewInt(100000)) != 0 {
+		t.Fatalf("cost mismatch: have %v, want %v", cost, 100000)
+	}
+	for name, gp := range map[string]*gadget.GasPrice{"nil": nil, "empty": {}} {
+		tx = types.NewNormalTransaction(1, common.Address{0x01}, big.NewInt(1), 100000, gp, nil, testSigner, key)
+		if err := pool.addRemoteSync(tx); !errors.Is(err, ErrMissingGasPrice) {
+			t.Errorf("%s gas price error mismatch: have %v, want %v", name, err, ErrMissingGasPrice)
+		}
+	}
+	withdraw := types.NewWithdrawTransaction(1, nil, []gadget.OutputCoin{{Amount: big.NewInt(1), Owner: common.Address{0x01}}}, testSigner, key)
+	if err := pool.addRemoteSync(withdraw); !errors.Is(err, ErrMissingGasPrice) {
+		t.Errorf("withdrawal gas price error mismatch: have %v, want %v", err, ErrMissingGasPrice)
+	}
+}
+
+// Tests that withdrawals pass the same checks as normal transactions, their
+// output coins counting towards their cost.
+func TestInvalidWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	withdraw := func(nonce uint64, price int64, amount int64) *types.Transaction {
+		outputs := []gadget.OutputCoin{{Amount: big.NewInt(amount), Owner: common.Address{0x01}}}
+		return types.NewWithdrawTransaction(nonce, gadget.NewGasPrice(big.NewInt(price)), outputs, testSigner, key)
+	}
+	// Insufficient funds for the output coins
+	testAddBalance(pool, from, big.NewInt(1000))
+	if err, want := pool.addRemote(withdraw(0, 1, 1001)), ErrInsufficientFunds; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.addRemote(withdraw(0, 1, -1)), ErrNegativeValue; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	pool.gasTip.Store(big.NewInt(1000))
+	if err, want := pool.addRemote(withdraw(0, 1, 1000)), ErrUnderpriced; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	pool.gasTip.Store(big.NewInt(1))
+	if err := pool.addRemoteSync(withdraw(0, 1, 1000)); err != nil {
+		t.Fatalf("failed to add funded withdrawal: %v", err)
+	}
+	// The withdrawal spent the funds of the account
+	if err, want := pool.addRemote(withdraw(1, 1, 1)), ErrInsufficientFunds; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	testSetNonce(pool, from, 1)
+	if err, want := pool.addRemote(withdraw(0, 1, 500)), ErrNonceTooLow; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+}
+
+// Tests that transactions whose hash doesn't match their content, or whose
+// signature wasn't made by their claimed sender, are rejected.
+func TestInvalidSenderBinding(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	forger, _ := crypto.GenerateKey()
+	victim := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, victim, big.NewInt(1000000000))
+
+	// Modifying the body without rehashing breaks the hash binding
+	tx := transaction(0, 100000, key)
+	tx.Value = big.NewInt(1000)
+	if err := pool.addRemote(tx); !errors.Is(err, ErrInvalidSender) {
+		t.Errorf("tampered transaction error mismatch: have %v, want %v", err, ErrInvalidSender)
+	}
+	// Rehashing and resigning with another key breaks the sender binding
+	tx = transaction(0, 100000, forger)
+	tx.From = victim
+	tx.Sign(testSigner, forger)
+	if err := pool.addRemote(tx); !errors.Is(err, ErrSenderMismatch) {
+		t.Errorf("forged transaction error mismatch: have %v, want %v", err, ErrSenderMismatch)
+	}
+	// The genuine transaction passes, twice through the sender cache
+	tx = transaction(0, 100000, key)
+	if err := pool.addRemote(tx); err != nil {
+		t.Fatalf("failed to add genuine transaction: %v", err)
+	}
+	if from, err := types.Sender(testSigner, tx); err != nil || from != victim {
+		t.Errorf("cached sender mismatch: have %v (%v), want %v", from, err, victim)
+	}
+}
+
+// Tests that senders recovered concurrently for a batch are matched up with the
+// right transactions, and errors land in the right slots.
+func TestBatchSenderRecovery(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupPool()
+	defer pool.Close()
+
+	var (
+		keys = make([]*ecdsa.PrivateKey, 64)
+		txs  = make(types.Transactions, len(keys))
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+		txs[i] = transaction(0, 100000, keys[i])
+	}
+	// Swap the senders of two transactions and duplicate a third one
+	txs[10].From, txs[20].From = txs[20].From, txs[10].From
+	txs[30] = txs[29]
+
+	for i, err := range pool.addRemotesSync(txs) {
+		switch i {
+		case 10, 20:
+			if !errors.Is(err, ErrInvalidSender) {
+				t.Errorf("transaction %d error mismatch: have %v, want %v", i, err, ErrInvalidSender)
+			}
+		case 30:
+			if !errors.Is(err, ErrAlreadyKnown) {
+				t.Errorf("transaction %d error mismatch: have %v, want %v", i, err, ErrAlreadyKnown)
+			}
+		default:
+			if err != nil {
+				t.Errorf("transaction %d rejected: %v", i, err)
+			}
+		}
+	}
+	if pending, _ := pool.Stats(); pending != len(txs)-3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, len(txs)-3)
+	}
+}
+
 func TestQueue(t *testing.T) {
 	t.Parallel()
 
@@ -371,7 +559,7 @@ func TestNegativeValue(t *testing.T) {
 	pool, key := setupPool()
 	defer pool.Close()
 	gp := gadget.NewGasPrice(big.NewInt(1))
-	tx := types.NewNormalTransaction(0, common.Address{}, big.NewInt(-100), 100, gp, nil, key)
+	tx := types.NewNormalTransaction(0, common.Address{}, big.NewInt(-100), 100, gp, nil, testSigner, key)
 	from, _ := deriveSender(tx)
 	testAddBalance(pool, from, big.NewInt(1))
 	if err := pool.addRemote(tx); err != ErrNegativeValue {
@@ -396,14 +584,14 @@ func TestChainFork(t *testing.T) {
 	}
 	resetState()
 
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	pool.removeTx(tx.TxHash, true)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -428,15 +616,15 @@ func TestDoubleNonce(t *testing.T) {
 
 	gp1 := gadget.NewGasPrice(big.NewInt(1))
 	gp2 := gadget.NewGasPrice(big.NewInt(2))
-	tx1 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 100000, gp1, nil, key)
-	tx2 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 1000000, gp2, nil, key)
-	tx3 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 1000000, gp1, nil, key)
+	tx1 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 100000, gp1, nil, testSigner, key)
+	tx2 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 1000000, gp2, nil, testSigner, key)
+	tx3 := types.NewNormalTransaction(0, common.Address{}, big.NewInt(100), 1000000, gp1, nil, testSigner, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
+	if replace, err := pool.add(tx1, false, false); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2, false); err != nil || !replace {
+	if replace, err := pool.add(tx2, false, false); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	<-pool.requestPromoteExecutables(newAccountSet(addr))
@@ -448,7 +636,7 @@ func TestDoubleNonce(t *testing.T) {
 	}
 
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	pool.add(tx3, false, false)
 	<-pool.requestPromoteExecutables(newAccountSet(addr))
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -471,7 +659,7 @@ func TestMissingNonce(t *testing.T) {
 	tx := transaction(1, 100000, key)
 	addr := tx.From
 	testAddBalance(pool, addr, big.NewInt(100000000000000))
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -1805,6 +1993,78 @@ func TestReplacement(t *testing.T) {
 	}
 }
 
+// Tests that dynamic fee transactions are only replaced if both the fee cap and
+// the tip cap are bumped, and that inverted caps are rejected.
+func TestReplacementDynamicFee(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addRemoteSync(dynamicFeeTransaction(0, 100000, big.NewInt(100), big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to add original pending transaction: %v", err)
+	}
+	if err := pool.addRemote(dynamicFeeTransaction(0, 100000, big.NewInt(200), big.NewInt(2), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("fee cap only replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.addRemote(dynamicFeeTransaction(0, 100000, big.NewInt(100), big.NewInt(20), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("tip cap only replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.addRemote(dynamicFeeTransaction(0, 100000, big.NewInt(110), big.NewInt(3), key)); err != nil {
+		t.Fatalf("failed to replace original pending transaction: %v", err)
+	}
+	if err := pool.addRemote(dynamicFeeTransaction(1, 100000, big.NewInt(1), big.NewInt(2), key)); !errors.Is(err, ErrTipAboveFeeCap) {
+		t.Fatalf("inverted caps error mismatch: have %v, want %v", err, ErrTipAboveFeeCap)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the priced heap orders transactions by effective tip once a base
+// fee is known, and that a raised tip threshold drops remotes by tip cap.
+func TestPricedListBaseFee(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupPool()
+	defer pool.Close()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// Highest fee cap but lowest effective tip at base fee 100
+	txs := types.Transactions{
+		dynamicFeeTransaction(0, 100000, big.NewInt(1000), big.NewInt(2), keys[0]),
+		dynamicFeeTransaction(0, 100000, big.NewInt(110), big.NewInt(10), keys[1]),
+		dynamicFeeTransaction(0, 100000, big.NewInt(150), big.NewInt(50), keys[2]),
+	}
+	for i, err := range pool.addRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	pool.mu.Lock()
+	pool.priced.SetBaseFee(big.NewInt(100))
+	worst := pool.priced.urgent.list[0]
+	if len(pool.priced.floating.list) > 0 && pool.priced.floating.cmp(pool.priced.floating.list[0], worst) < 0 {
+		worst = pool.priced.floating.list[0]
+	}
+	pool.mu.Unlock()
+	if worst.TxHash != txs[0].TxHash {
+		t.Fatalf("cheapest transaction mismatch: have %x, want %x", worst.TxHash, txs[0].TxHash)
+	}
+	pool.SetGasTip(big.NewInt(5))
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if pool.Has(txs[0].TxHash) {
+		t.Fatalf("transaction below tip threshold not dropped")
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestJournaling(t *testing.T)         { testJournaling(t, false) }
@@ -1921,6 +2181,209 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Close()
 }
 
+// Tests that both local and remote transactions survive restarts through the
+// pool store, and that restored transactions are re-validated against the head.
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	statedb := newStateEnv().state
+	blockchain := NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Store = t.TempDir()
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("transaction count mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	// Terminate the pool, include the first remote transaction and restart
+	pool.Close()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("transaction count mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	if locals := pool.Locals(); len(locals) != 1 || locals[0] != crypto.PubkeyToAddress(local.PublicKey) {
+		t.Fatalf("local accounts mismatch: have %v", locals)
+	}
+	stored := 0
+	rawdb.IteratePooledTransactions(pool.store.db, func(common.Hash, []byte) bool {
+		stored++
+		return true
+	})
+	if stored != 2 {
+		t.Fatalf("stored transaction count mismatch: have %d, want %d", stored, 2)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that remote senders and peers are throttled by their token buckets, that
+// replacements honour the minimum interval and that locals and transactions added
+// back by the pool itself are exempt.
+func TestRateLimiting(t *testing.T) {
+	t.Parallel()
+
+	statedb := newStateEnv().state
+	blockchain := NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.AccountRate, config.AccountBurst = 0.001, 2
+	config.PeerRate, config.PeerBurst = 0.001, 3
+	config.ReplaceInterval = time.Hour
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// The sender burst allows two transactions, the third one is rejected
+	addRemote := func(tx *types.Transaction) error {
+		return pool.Add(types.Transactions{tx}, false, true)[0]
+	}
+	if err := addRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[0])); err != nil {
+		t.Fatalf("failed to add first transaction: %v", err)
+	}
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), keys[0])
+	if err := addRemote(replacement); err != nil {
+		t.Fatalf("failed to replace first transaction: %v", err)
+	}
+	// Replacing again within the interval fails before touching the bucket
+	if err := addRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[0])); !errors.Is(err, ErrReplaceTooSoon) {
+		t.Fatalf("second replacement error mismatch: have %v, want %v", err, ErrReplaceTooSoon)
+	}
+	throttled := pricedTransaction(1, 100000, big.NewInt(1), keys[0])
+	if err := addRemote(throttled); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("third transaction error mismatch: have %v, want %v", err, ErrRateLimited)
+	}
+	// Transactions the pool adds back on its own, e.g. from the store, are not
+	// throttled
+	if err := pool.addRemoteSync(throttled); err != nil {
+		t.Fatalf("failed to restore throttled transaction: %v", err)
+	}
+	// Neither do they start the replacement interval, queued or pending
+	if err := pool.addRemoteSync(pricedTransaction(5, 100000, big.NewInt(1), keys[4])); err != nil {
+		t.Fatalf("failed to restore queued transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(5, 100000, big.NewInt(2), keys[4])); err != nil {
+		t.Fatalf("failed to restore queued replacement: %v", err)
+	}
+	if err := addRemote(pricedTransaction(5, 100000, big.NewInt(3), keys[4])); err != nil {
+		t.Fatalf("failed to replace restored queued transaction: %v", err)
+	}
+	if err := addRemote(pricedTransaction(5, 100000, big.NewInt(4), keys[4])); !errors.Is(err, ErrReplaceTooSoon) {
+		t.Fatalf("queued replacement error mismatch: have %v, want %v", err, ErrReplaceTooSoon)
+	}
+	// Local transactions are not throttled
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.addLocal(pricedTransaction(i, 100000, big.NewInt(1), keys[1])); err != nil {
+			t.Fatalf("failed to add local transaction %d: %v", i, err)
+		}
+	}
+	// The peer burst allows three new transactions, known ones are free
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), keys[2]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[2]),
+		replacement,
+		pricedTransaction(0, 100000, big.NewInt(1), keys[3]),
+		pricedTransaction(1, 100000, big.NewInt(1), keys[3]),
+	}
+	want := []error{nil, nil, ErrAlreadyKnown, nil, ErrRateLimited}
+	for i, err := range pool.AddFromPeer(txs, "peer", true) {
+		if !errors.Is(err, want[i]) {
+			t.Errorf("transaction %d error mismatch: have %v, want %v", i, err, want[i])
+		}
+	}
+	if peers := pool.peerLimiter.prune(time.Now()); peers != 1 {
+		t.Errorf("tracked peer count mismatch: have %d, want %d", peers, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the inspection API reports counts, gaps, costs and flags matching
+// the pool contents.
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	local, _ := crypto.GenerateKey()
+	addr, localAddr := crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(local.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+	testAddBalance(pool, localAddr, big.NewInt(1000000000))
+
+	// Two pending transactions, then a gap at nonce 2 and a queued one
+	txs := types.Transactions{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(2), key),
+		pricedTransaction(3, 100000, big.NewInt(3), key),
+	}
+	for _, err := range pool.addRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if err := pool.addLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	accounts := pool.Inspect()
+	if len(accounts) != 2 {
+		t.Fatalf("inspected account count mismatch: have %d, want %d", len(accounts), 2)
+	}
+	status := accounts[addr]
+	if status.Pending != 2 || status.Queued != 1 {
+		t.Errorf("transaction count mismatch: have %d/%d, want %d/%d", status.Pending, status.Queued, 2, 1)
+	}
+	if status.Nonce != 0 || status.FirstGap != 2 {
+		t.Errorf("nonce mismatch: have nonce %d gap %d, want nonce %d gap %d", status.Nonce, status.FirstGap, 0, 2)
+	}
+	if want := new(big.Int).Add(txs[0].Cost(), txs[1].Cost()); status.PendingCost.Cmp(want) != 0 {
+		t.Errorf("pending cost mismatch: have %v, want %v", status.PendingCost, want)
+	}
+	if status.QueuedCost.Cmp(txs[2].Cost()) != 0 {
+		t.Errorf("queued cost mismatch: have %v, want %v", status.QueuedCost, txs[2].Cost())
+	}
+	if status.Slots != 3 || status.Local || status.Heartbeat.IsZero() {
+		t.Errorf("account status mismatch: %+v", status)
+	}
+	if status := pool.InspectFrom(localAddr); status == nil || !status.Local || status.FirstGap != 1 {
+		t.Errorf("local account status mismatch: %+v", status)
+	}
+	if status := pool.InspectFrom(common.Address{0x01}); status != nil {
+		t.Errorf("unknown account inspected: %+v", status)
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -2116,7 +2579,7 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 }
 
 func pricedValuedTransaction(nonce uint64, value int64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
-	tx := types.NewNormalTransaction(nonce, common.Address{}, big.NewInt(value), gaslimit, &gadget.GasPrice{Price: gasprice}, nil, key)
+	tx := types.NewNormalTransaction(nonce, common.Address{}, big.NewInt(value), gaslimit, &gadget.GasPrice{Price: gasprice}, nil, testSigner, key)
 	return tx
 }
 
@@ -2370,3 +2833,50 @@ func TestMuteTransaction(t *testing.T) {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 }
+
+// Tests that transactions past their deadline are refused, dropped on reset with
+// their successors demoted, and that the deadline can't be changed without
+// invalidating the signature.
+func TestTransactionExpiry(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	deadline := func(nonce uint64, until gadget.Deadline) *types.Transaction {
+		tx := transaction(nonce, 100000, key)
+		tx.ValidUntil = &until
+		tx.Sign(testSigner, key)
+		return tx
+	}
+	if err := pool.addRemote(deadline(0, gadget.Deadline{Time: 1})); !errors.Is(err, types.ErrTxExpired) {
+		t.Fatalf("expired transaction error mismatch: have %v, want %v", err, types.ErrTxExpired)
+	}
+	tampered := deadline(0, gadget.Deadline{Number: 1})
+	tampered.ValidUntil = &gadget.Deadline{Number: 100}
+	if err := pool.addRemote(tampered); !errors.Is(err, ErrInvalidSender) {
+		t.Fatalf("tampered deadline error mismatch: have %v, want %v", err, ErrInvalidSender)
+	}
+	// Pool a transaction which only fits into the next block between two others
+	expiring := deadline(1, gadget.Deadline{Number: 1})
+	if errs := pool.addRemotesSync([]*types.Transaction{transaction(0, 100000, key), expiring, transaction(2, 100000, key)}); errs[0] != nil || errs[1] != nil || errs[2] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 3/0", pending, queued)
+	}
+	<-pool.requestReset(nil, types.NewHeader(common.Hash{}, big.NewInt(1), 1000000))
+
+	if pool.Get(expiring.TxHash) != nil {
+		t.Fatalf("expired transaction still pooled")
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 1/1", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
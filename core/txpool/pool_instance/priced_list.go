@@ -3,7 +3,7 @@ package txpool_instance
 import (
 	"container/heap"
 	"execution/common"
-	"execution/core/types"
+	"execution/types"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -11,12 +11,14 @@ import (
 )
 
 // priceHeap is a heap.Interface implementation over transactions for retrieving
-// price-sorted transactions to discard when the pool fills up. If baseFee is set
-// then the heap is sorted based on the effective tip based on the given base fee.
-// If baseFee is nil then the sorting is based on gasFeeCap.
+// the least preferred transactions to discard when the pool fills up. The order
+// is defined by the ordering policy, which is given the base fee if set.
 type priceHeap struct {
 	baseFee *big.Int // heap should always be re-sorted after baseFee is changed
 	list    []*types.Transaction
+
+	policy OrderingPolicy                     // Policy deciding which transactions are preferred
+	ahead  func(tx *types.Transaction) uint64 // Number of transactions of the sender executing before tx
 }
 
 func (h *priceHeap) Len() int      { return len(h.list) }
@@ -34,7 +36,7 @@ func (h *priceHeap) Less(i, j int) bool {
 }
 
 func (h *priceHeap) cmp(a, b *types.Transaction) int {
-	return a.GasPrice.Price.Cmp(b.GasPrice.Price)
+	return h.policy.Cmp(a, b, h.ahead(a), h.ahead(b), h.baseFee)
 }
 
 func (h *priceHeap) Push(x interface{}) {
@@ -62,13 +64,17 @@ func (h *priceHeap) Pop() interface{} {
 // In some cases (during a congestion, when blocks are full) the urgent heap can provide
 // better candidates for inclusion while in other cases (at the top of the baseFee peak)
 // the floating heap is better. When baseFee is decreasing they behave similarly.
+//
+// Both heaps are sorted by the ordering policy of the pool. Policies which don't
+// look at the base fee sort them the same way.
 type PricedList struct {
 	// Number of stale price points to (re-heap trigger).
 	stales atomic.Int64
 
-	all              *Lookup    // Pointer to the map of all transactions
-	urgent, floating priceHeap  // Heaps of prices of all the stored **remote** transactions
-	reheapMu         sync.Mutex // Mutex asserts that only one routine is reheaping the list
+	all              *Lookup                          // Pointer to the map of all transactions
+	urgent, floating priceHeap                        // Heaps of prices of all the stored **remote** transactions
+	exempt           func(tx *types.Transaction) bool // Remote transactions exempt from eviction, never tracked
+	reheapMu         sync.Mutex                       // Mutex asserts that only one routine is reheaping the list
 }
 
 const (
@@ -77,16 +83,32 @@ const (
 	floatingRatio = 1
 )
 
-// newPricedList creates a new price-sorted transaction heap.
-func NewPricedList(all *Lookup) *PricedList {
+// NewPricedList creates a new transaction heap sorted by the given ordering
+// policy, falling back to sorting by price if nil. The ahead function tells the
+// policy how many transactions of the same sender execute before a given one.
+// Remote transactions matching the optional exempt function are never tracked,
+// like local ones.
+func NewPricedList(all *Lookup, policy OrderingPolicy, ahead func(tx *types.Transaction) uint64, exempt func(tx *types.Transaction) bool) *PricedList {
+	if policy == nil {
+		policy = feeOrdering{}
+	}
+	if ahead == nil {
+		ahead = func(*types.Transaction) uint64 { return 0 }
+	}
+	if exempt == nil {
+		exempt = func(*types.Transaction) bool { return false }
+	}
 	return &PricedList{
-		all: all,
+		all:      all,
+		exempt:   exempt,
+		urgent:   priceHeap{policy: policy, ahead: ahead},
+		floating: priceHeap{policy: policy, ahead: ahead},
 	}
 }
 
 // Put inserts a new transaction into the heap.
 func (l *PricedList) Put(tx *types.Transaction, local bool) {
-	if local {
+	if local || l.exempt(tx) {
 		return
 	}
 	// Insert every new transaction to the urgent heap first; Discard will balance the heaps
@@ -189,7 +211,9 @@ func (l *PricedList) Reheap() {
 	l.stales.Store(0)
 	l.urgent.list = make([]*types.Transaction, 0, l.all.RemoteCount())
 	l.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
-		l.urgent.list = append(l.urgent.list, tx)
+		if !l.exempt(tx) {
+			l.urgent.list = append(l.urgent.list, tx)
+		}
 		return true
 	}, false, true) // Only iterate remotes
 	heap.Init(&l.urgent)
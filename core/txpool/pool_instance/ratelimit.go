@@ -0,0 +1,79 @@
+package txpool_instance
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket is the state of a single rate limited key.
+type tokenBucket struct {
+	tokens float64   // Tokens available for spending
+	last   time.Time // Time the tokens were last refilled
+}
+
+// rateLimiter is a set of token buckets, one per key, each refilling at a fixed
+// rate up to a maximum burst. Each allowed event spends one token. A limiter
+// with a non-positive rate allows everything.
+type rateLimiter[K comparable] struct {
+	rate    float64 // Tokens added to each bucket per second
+	burst   float64 // Maximum number of tokens a bucket can hold
+	buckets map[K]*tokenBucket
+	lock    sync.Mutex
+}
+
+// newRateLimiter creates a limiter refilling rate tokens per second, up to burst.
+func newRateLimiter[K comparable](rate float64, burst uint64) *rateLimiter[K] {
+	return &rateLimiter[K]{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[K]*tokenBucket),
+	}
+}
+
+// allow spends a token from the bucket of the given key, reporting whether one
+// was available.
+func (l *rateLimiter[K]) allow(key K, now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket := l.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	} else {
+		l.refill(bucket, now)
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// refill tops up the bucket with the tokens accrued since its last refill.
+func (l *rateLimiter[K]) refill(bucket *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * l.rate
+		if bucket.tokens > l.burst {
+			bucket.tokens = l.burst
+		}
+		bucket.last = now
+	}
+}
+
+// prune drops the buckets that refilled completely, since those are identical
+// to freshly created ones, and returns the number of buckets still tracked.
+func (l *rateLimiter[K]) prune(now time.Time) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for key, bucket := range l.buckets {
+		if l.refill(bucket, now); bucket.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	return len(l.buckets)
+}
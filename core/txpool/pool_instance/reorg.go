@@ -0,0 +1,137 @@
+package txpool_instance
+
+import (
+	"errors"
+
+	"execution/common"
+	"execution/core/rawdb"
+	"execution/ethdb"
+	"execution/types"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// reorgBatchBlocks is the number of discarded blocks whose transactions are
+// reinjected at once, bounding the memory used by deep reorgs.
+const reorgBatchBlocks = 64
+
+// ChainIndex is optionally implemented by the chain the pool runs on. With it,
+// the pool reinjects the transactions of reorgs of any depth, instead of
+// skipping those deeper than 64 blocks.
+type ChainIndex interface {
+	// GetCanonicalHash returns the hash of the canonical block at the given
+	// height, or the zero hash if there is none.
+	GetCanonicalHash(number uint64) common.Hash
+
+	// GetTxLookup returns the number of the canonical block including the given
+	// transaction, or nil if it isn't indexed.
+	GetTxLookup(hash common.Hash) *uint64
+}
+
+// DatabaseIndex is a ChainIndex reading the canonical hashes and transaction
+// lookup entries of a chain database.
+type DatabaseIndex struct {
+	db ethdb.Reader
+}
+
+// NewDatabaseIndex creates a chain index over the given database.
+func NewDatabaseIndex(db ethdb.Reader) *DatabaseIndex {
+	return &DatabaseIndex{db: db}
+}
+
+// GetCanonicalHash implements ChainIndex.
+func (i *DatabaseIndex) GetCanonicalHash(number uint64) common.Hash {
+	return rawdb.ReadCanonicalHash(i.db, number)
+}
+
+// GetTxLookup implements ChainIndex.
+func (i *DatabaseIndex) GetTxLookup(hash common.Hash) *uint64 {
+	return rawdb.ReadTxLookupEntry(i.db, hash)
+}
+
+// blockRef identifies a block of a discarded chain.
+type blockRef struct {
+	hash   common.Hash
+	number uint64
+}
+
+// forkedBlocks walks the old chain back from oldHead to its lowest common
+// ancestor with the canonical chain, the first block which is canonical again,
+// and returns the blocks in between, oldest first. Only headers are loaded and
+// only the hashes kept, so the walk is cheap at any depth. If the old chain can't be walked down to the
+// ancestor, the blocks found so far are returned along with false.
+func (pool *LegacyPool) forkedBlocks(index ChainIndex, oldHead *types.Header) ([]blockRef, bool) {
+	var (
+		branch []blockRef
+		hash   = oldHead.Hash()
+		number = oldHead.Number().Uint64()
+	)
+	for index.GetCanonicalHash(number) != hash {
+		header := pool.chain.GetHeader(hash, number)
+		if header == nil || number == 0 {
+			return reverseRefs(branch), false
+		}
+		branch = append(branch, blockRef{hash: hash, number: number})
+		hash, number = header.ParentHash(), number-1
+	}
+	return reverseRefs(branch), true
+}
+
+func reverseRefs(refs []blockRef) []blockRef {
+	for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+		refs[i], refs[j] = refs[j], refs[i]
+	}
+	return refs
+}
+
+// reinjectBlocks reinjects the transactions of the given discarded blocks which
+// the canonical chain up to newNum doesn't include, in batches of
+// reorgBatchBlocks blocks. It returns the number of transactions back in the
+// pool and of those lost, e.g. because the new chain used their nonces up. The
+// pool lock must be held and the pool reset to the new head.
+func (pool *LegacyPool) reinjectBlocks(index ChainIndex, blocks []blockRef, newNum uint64) (reinjected, lost int) {
+	for start := 0; start < len(blocks); start += reorgBatchBlocks {
+		end := start + reorgBatchBlocks
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		var batch types.Transactions
+		for _, ref := range blocks[start:end] {
+			block := pool.chain.GetBlock(ref.hash, ref.number)
+			if block == nil {
+				log.Warn("Discarded block missing in txpool reset", "number", ref.number, "hash", ref.hash)
+				continue
+			}
+			for _, tx := range block.Transactions() {
+				if number := index.GetTxLookup(tx.TxHash); number != nil && *number <= newNum {
+					continue // Included in the new chain
+				}
+				batch = append(batch, tx)
+			}
+		}
+		r, l := pool.reinject(batch)
+		reinjected, lost = reinjected+r, lost+l
+	}
+	return reinjected, lost
+}
+
+// reinject adds transactions discarded by a reorg back to the pool, returning
+// the number of them back in the pool and of those refused. The pool lock must
+// be held.
+func (pool *LegacyPool) reinject(txs types.Transactions) (reinjected, lost int) {
+	if len(txs) == 0 {
+		return 0, 0
+	}
+	types.SenderCacher.Recover(pool.signer, txs)
+	errs, _ := pool.addTxsLocked(txs, false, false)
+	for _, err := range errs {
+		if err == nil || errors.Is(err, ErrAlreadyKnown) {
+			reinjected++
+		} else {
+			lost++
+		}
+	}
+	reorgReinjectedMeter.Mark(int64(reinjected))
+	reorgLostMeter.Mark(int64(lost))
+	return reinjected, lost
+}
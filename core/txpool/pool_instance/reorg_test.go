@@ -0,0 +1,117 @@
+package txpool_instance
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/core/rawdb"
+	"execution/crypto"
+	"execution/types"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// indexedBlockChain is a test chain keeping blocks of several branches and a
+// database index of the canonical one.
+type indexedBlockChain struct {
+	*EasyBlockChain
+	*DatabaseIndex
+	blocks map[common.Hash]*types.Block
+	bodies int // Number of blocks loaded with their bodies
+}
+
+func (bc *indexedBlockChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := bc.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block.Header()
+	}
+	return nil
+}
+
+func (bc *indexedBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := bc.blocks[hash]; block != nil && block.NumberU64() == number {
+		bc.bodies++
+		return block
+	}
+	return nil
+}
+
+// extend appends a block with the given transactions on top of parent to the
+// chain, without making it canonical.
+func (bc *indexedBlockChain) extend(parent *types.Header, branch byte, txs types.Transactions) *types.Header {
+	number := new(big.Int).Add(parent.Number(), common.Big1)
+	body := types.NewBody(txs)
+
+	header := types.NewHeader(parent.Hash(), number, parent.GasLimit()).WithProposal(common.Address{branch}, 0, nil).WithBody(body)
+	bc.blocks[header.Hash()] = types.NewBlock(header, body)
+	return header
+}
+
+// Tests that the transactions of a reorg deeper than 64 blocks are reinjected
+// when the chain has an index, except those the new chain includes.
+func TestDeepReorgReinjection(t *testing.T) {
+	t.Parallel()
+
+	const depth = 100
+
+	var (
+		statedb = newStateEnv().state
+		db      = rawdb.NewMemoryDatabase()
+		chain   = &indexedBlockChain{
+			EasyBlockChain: NewEasyBlockChain(nil, 1000000, statedb, new(event.Feed)),
+			DatabaseIndex:  NewDatabaseIndex(db),
+			blocks:         make(map[common.Hash]*types.Block),
+		}
+		genesis = chain.CurrentBlock()
+	)
+	chain.blocks[genesis.Hash()] = types.NewBlock(genesis, types.NewBody(nil))
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	pool := New(testTxPoolConfig, chain)
+	pool.Init(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), genesis)
+	defer pool.Close()
+
+	// Build the old chain with a transaction of a distinct account per block
+	var (
+		keys = make([]*ecdsa.PrivateKey, depth)
+		txs  = make(types.Transactions, depth)
+	)
+	oldHead := genesis
+	for i := 0; i < depth; i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+
+		txs[i] = transaction(0, 100000, keys[i])
+		oldHead = chain.extend(oldHead, 0x01, types.Transactions{txs[i]})
+	}
+	// Build a longer canonical chain including the first two of them
+	newHead := chain.extend(genesis, 0x02, txs[:2])
+	rawdb.WriteCanonicalHash(db, newHead.Hash(), 1)
+	rawdb.WriteTxLookupEntries(db, 1, []common.Hash{txs[0].TxHash, txs[1].TxHash})
+	for i := 1; i <= depth; i++ {
+		newHead = chain.extend(newHead, 0x02, nil)
+		rawdb.WriteCanonicalHash(db, newHead.Hash(), newHead.Number().Uint64())
+	}
+	<-pool.requestReset(oldHead, newHead)
+
+	// Only the discarded blocks have their bodies loaded, the walk uses headers
+	if chain.bodies != depth {
+		t.Errorf("loaded bodies mismatch: have %d, want %d", chain.bodies, depth)
+	}
+	pending, queued := pool.Stats()
+	if pending != depth-2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, depth-2)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	for i, tx := range txs {
+		if known, want := pool.Has(tx.TxHash), i >= 2; known != want {
+			t.Errorf("transaction %d presence mismatch: have %v, want %v", i, known, want)
+		}
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
@@ -1,19 +1,21 @@
 package txpool_instance
 
 import (
-	"execution/core/types"
+	"execution/types"
+	"math"
 	"math/big"
+	"sort"
 )
 
 type SortedMap struct {
 	items map[uint64]*types.Transaction // Hash map storing the transaction data
-	tree  *AVLTree                      // AVL tree of nonces of all the stored transactions (non-strict mode)
+	tree  *CostTree                     // AVL tree of the costs of all the stored transactions, indexed by nonce
 }
 
 func NewSortedMap() *SortedMap {
 	return &SortedMap{
 		items: make(map[uint64]*types.Transaction),
-		tree:  new(AVLTree),
+		tree:  new(CostTree),
 	}
 }
 
@@ -90,37 +92,97 @@ func (m *SortedMap) Remove(nonce uint64) bool {
 	return true
 }
 
-// Given the provided start nonce, Ready returns
-// transactions that are continous, the varible start is the virtual nonce.
+// Ready returns and removes the transactions that are contiguous from the given
+// start nonce, the virtual nonce of the account, and whose cumulative cost fits
+// within the threshold. The affordable prefix is found by a binary search over
+// the cost index, without walking the transactions.
 func (m *SortedMap) Ready(start uint64, threshold *big.Int) types.Transactions {
-	size := len(m.items)
-	if size == 0 {
+	last, ok := m.lastReady(start, threshold)
+	if !ok {
+		return nil
+	}
+	return m.SplitBelow(last)
+}
+
+// PeekReady returns the transactions Ready would, without removing them.
+func (m *SortedMap) PeekReady(start uint64, threshold *big.Int) types.Transactions {
+	last, ok := m.lastReady(start, threshold)
+	if !ok {
 		return nil
 	}
+	var txs types.Transactions
+	m.tree.AscendRange(0, last, func(nonce uint64, _ *big.Int) bool {
+		txs = append(txs, m.items[nonce])
+		return true
+	})
+	return txs
+}
+
+// lastReady returns the highest nonce of the transactions ready for processing
+// from the given start nonce within the threshold, if there are any.
+func (m *SortedMap) lastReady(start uint64, threshold *big.Int) (uint64, bool) {
 	smallest, err := m.tree.Smallest()
 	if smallest > start || err != nil {
-		return nil
+		return 0, false
+	}
+	last, ok := m.MaxAffordable(threshold)
+	if !ok {
+		return 0, false
 	}
+	// Only a contiguous run of nonces can be executed
+	if contiguous := m.contiguous(smallest); smallest+uint64(contiguous)-1 < last {
+		last = smallest + uint64(contiguous) - 1
+	}
+	return last, true
+}
 
-	var ready types.Transactions
-	tx := m.items[smallest]
-	total := new(big.Int).Set(tx.Cost())
+// contiguous returns the number of transactions with consecutive nonces from
+// the given smallest one.
+func (m *SortedMap) contiguous(smallest uint64) int {
+	return sort.Search(m.tree.Len(), func(i int) bool {
+		node, _ := m.tree.Kth(i)
+		return node.Key()-smallest != uint64(i)
+	})
+}
 
-	for next := smallest; size > 0 && smallest == next && total.Cmp(threshold) <= 0; next++ {
-		ready = append(ready, m.items[next])
-		m.tree.Remove(smallest)
-		delete(m.items, smallest)
-		size--
+// SplitBelow removes and returns all the transactions with a nonce up to and
+// including the given one, sorted by nonce.
+func (m *SortedMap) SplitBelow(nonce uint64) types.Transactions {
+	if nonce == math.MaxUint64 {
+		return m.take(m.tree.Split(0))
+	}
+	rest := m.tree.Split(nonce + 1)
+	head := m.tree
+	m.tree = rest
+	return m.take(head)
+}
 
-		smallest, err = m.tree.Smallest()
-		if err != nil {
-			break
-		}
-		tx = m.items[smallest]
-		total = total.Add(total, tx.Cost())
+// SplitAbove removes and returns all the transactions with a nonce strictly
+// higher than the given one, sorted by nonce.
+func (m *SortedMap) SplitAbove(nonce uint64) types.Transactions {
+	if nonce == math.MaxUint64 {
+		return nil
 	}
+	return m.take(m.tree.Split(nonce + 1))
+}
 
-	return ready
+// take removes the transactions indexed by a tree split off the map.
+func (m *SortedMap) take(tree *CostTree) types.Transactions {
+	txs := make(types.Transactions, 0, tree.Len())
+	tree.Ascend(func(nonce uint64, _ *big.Int) bool {
+		txs = append(txs, m.items[nonce])
+		delete(m.items, nonce)
+		return true
+	})
+	return txs
+}
+
+// AscendFrom calls fn for every transaction with a nonce of at least the given
+// one in nonce order, until fn returns false.
+func (m *SortedMap) AscendFrom(nonce uint64, fn func(tx *types.Transaction) bool) {
+	m.tree.AscendRange(nonce, math.MaxUint64, func(nonce uint64, _ *big.Int) bool {
+		return fn(m.items[nonce])
+	})
 }
 
 func (m *SortedMap) Len() int {
@@ -128,14 +190,26 @@ func (m *SortedMap) Len() int {
 }
 
 func (m *SortedMap) Flatten() types.Transactions {
-	nodes := m.tree.Flatten()
 	cache := make(types.Transactions, 0, len(m.items))
-	for _, node := range nodes {
-		cache = append(cache, m.items[node.key])
-	}
+	m.tree.Ascend(func(nonce uint64, _ *big.Int) bool {
+		cache = append(cache, m.items[nonce])
+		return true
+	})
 	return cache
 }
 
+// MaxAffordable returns the highest nonce such that the transactions up to and
+// including it cost no more than the given balance in total.
+func (m *SortedMap) MaxAffordable(balance *big.Int) (uint64, bool) {
+	node, ok := m.tree.MaxPrefix(func(sum *big.Int) bool {
+		return sum.Cmp(balance) <= 0
+	})
+	if !ok {
+		return 0, false
+	}
+	return node.Key(), true
+}
+
 func (m *SortedMap) LastElement() *types.Transaction {
 	last, err := m.tree.Largest()
 	if err != nil {
@@ -0,0 +1,134 @@
+package txpool_instance
+
+import (
+	"bytes"
+
+	"execution/common"
+	"execution/core/rawdb"
+	"execution/ethdb"
+	"execution/types"
+	"execution/utils"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// txStore is a disk-backed copy of the entire pool content, local and remote,
+// allowing the pool to survive node restarts. Unlike the journal, which appends
+// local transactions as they arrive, the store is only synced with the pool at
+// periodic checkpoints.
+type txStore struct {
+	db ethdb.Database // Key-value store holding the pooled transactions
+}
+
+// newTxStore opens the on-disk transaction store at the given path.
+func newTxStore(path string) (*txStore, error) {
+	db, err := rawdb.NewPebbleDBDatabase(path, 16, 16, "txpool/store/", false)
+	if err != nil {
+		return nil, err
+	}
+	return &txStore{db: db}, nil
+}
+
+// load reads all stored transactions and injects them into the pool through the
+// given add callbacks, which are expected to re-validate them against the
+// current head.
+func (store *txStore) load(addLocals, addRemotes func(types.Transactions) []error) error {
+	var (
+		serializer utils.JsonSerializer
+
+		locals, remotes types.Transactions
+		corrupt         []common.Hash
+	)
+	err := rawdb.IteratePooledTransactions(store.db, func(hash common.Hash, data []byte) bool {
+		if len(data) < 1 {
+			corrupt = append(corrupt, hash)
+			return true
+		}
+		tx := new(types.Transaction)
+		if err := serializer.GetDecoder(bytes.NewReader(data[1:]), uint64(len(data)-1), uint64(len(data)-1)).Decode(tx); err != nil {
+			log.Debug("Failed to decode stored transaction", "hash", hash, "err", err)
+			corrupt = append(corrupt, hash)
+			return true
+		}
+		if data[0] == 1 {
+			locals = append(locals, tx)
+		} else {
+			remotes = append(remotes, tx)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, hash := range corrupt {
+		rawdb.DeletePooledTransaction(store.db, hash)
+	}
+	dropped := 0
+	for _, errs := range [][]error{addLocals(locals), addRemotes(remotes)} {
+		for _, err := range errs {
+			if err != nil {
+				log.Debug("Failed to add stored transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	log.Info("Loaded transaction pool store", "locals", len(locals), "remotes", len(remotes), "dropped", dropped, "corrupt", len(corrupt))
+	return nil
+}
+
+// checkpoint syncs the store with the given pool contents, writing any new
+// transactions and deleting the ones no longer in the pool.
+func (store *txStore) checkpoint(all *Lookup) error {
+	var (
+		serializer utils.JsonSerializer
+
+		batch  = store.db.NewBatch()
+		live   = make(map[common.Hash]struct{}, all.Count())
+		stored = make(map[common.Hash]bool) // Stored transactions and their local flags
+	)
+	err := rawdb.IteratePooledTransactions(store.db, func(hash common.Hash, data []byte) bool {
+		stored[hash] = len(data) > 0 && data[0] == 1
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	var failure error
+	all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		live[hash] = struct{}{}
+		if wasLocal, ok := stored[hash]; ok && wasLocal == local {
+			return true
+		}
+		var buf bytes.Buffer
+		if local {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		if failure = serializer.GetEncoder(&buf).Encode(tx); failure != nil {
+			return false
+		}
+		rawdb.WritePooledTransaction(batch, hash, buf.Bytes())
+		return true
+	}, true, true)
+	if failure != nil {
+		return failure
+	}
+	deleted := 0
+	for hash := range stored {
+		if _, ok := live[hash]; !ok {
+			rawdb.DeletePooledTransaction(batch, hash)
+			deleted++
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Debug("Checkpointed transaction pool store", "transactions", len(live), "deleted", deleted)
+	return nil
+}
+
+// close releases the underlying database.
+func (store *txStore) close() error {
+	return store.db.Close()
+}
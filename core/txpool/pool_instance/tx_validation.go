@@ -1,18 +1,21 @@
 package txpool_instance
 
 import (
+	"bytes"
 	"execution/common"
 	"execution/core/state"
-	"execution/core/types"
+	"execution/types"
 	"fmt"
 	"math/big"
+	"time"
 )
 
 // ValidationOptions define certain differences between transaction validation
 // across the different pools without having to duplicate those checks.
 type ValidationOptions struct {
-	MaxSize uint64   // Maximum size of a transaction that the caller can meaningfully handle
-	MinTip  *big.Int // Minimum gas tip needed to allow a transaction into the caller pool
+	Signer  types.Signer // Signer of the chain to recover transaction senders with
+	MaxSize uint64       // Maximum size of a transaction that the caller can meaningfully handle
+	MinTip  *big.Int     // Minimum gas tip needed to allow a transaction into the caller pool
 }
 
 // ValidateTransaction is a helper method to check whether a transaction is valid
@@ -29,8 +32,14 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, opts *Valida
 	default:
 		return fmt.Errorf("%w: tx type not supported by this pool", ErrTxTypeNotSupported)
 	}
+	// Ensure the transaction can still be included in the next block
+	if err := tx.CheckDeadline(head.Number().Uint64()+1, uint64(time.Now().Unix())); err != nil {
+		return fmt.Errorf("%w: valid until block %d, time %d", err, tx.ValidUntil.Number, tx.ValidUntil.Time)
+	}
 
-	if tx.Type() == types.NormalTx {
+	// Withdrawals are charged to their sender like normal transactions, so they
+	// pass the same checks
+	if tx.Type() == types.NormalTx || tx.Type() == types.WithdrawTx {
 		// Before performing any expensive validations, sanity check that the tx is
 		// smaller than the maximum limit the pool can meaningfully handle
 		if tx.Size() > opts.MaxSize {
@@ -39,21 +48,38 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, opts *Valida
 
 		// Transactions can't be negative. This may never happen using RLP decoded
 		// transactions but may occur for transactions created using the RPC.
-		if tx.Value.Sign() < 0 {
+		if tx.Value != nil && tx.Value.Sign() < 0 {
 			return ErrNegativeValue
 		}
+		for i, output := range tx.OutputCoins {
+			if output.Amount == nil || output.Amount.Sign() < 0 {
+				return fmt.Errorf("%w: output %d amount %v", ErrNegativeValue, i, output.Amount)
+			}
+		}
+		// Decoded transactions may leave the gas price out, which every price
+		// check below relies on
+		if tx.GasPrice == nil || tx.GasPrice.GasFeeCap() == nil || tx.GasPrice.GasTipCap() == nil {
+			return ErrMissingGasPrice
+		}
 		// Ensure the transaction doesn't exceed the current block limit gas
 		if (*head).GasLimit() < tx.GasLimit {
 			return ErrGasLimit
 		}
 		// Sanity check for extremely large numbers (supported by RLP or RPC)
-		if tx.GasPrice.Price.BitLen() > 256 {
-			return ErrPriceVeryHigh
+		if tx.GasPrice.GasFeeCap().BitLen() > 256 {
+			return ErrFeeCapVeryHigh
+		}
+		if tx.GasPrice.GasTipCap().BitLen() > 256 {
+			return ErrTipVeryHigh
+		}
+		// Ensure gasFeeCap is greater than or equal to gasTipCap
+		if tx.GasPrice.GasFeeCapIntCmp(tx.GasPrice.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
 		}
 
-		// Make sure the transaction is signed properly
-		if _, err := tx.Validation.GetFrom(tx.TxHash); err != nil {
-			return ErrInvalidSender
+		// Make sure the transaction is signed properly by its claimed sender
+		if err := validateSender(opts.Signer, tx); err != nil {
+			return err
 		}
 		// Ensure the transaction has more gas than the bare minimum needed to cover
 		// the transaction metadata
@@ -64,11 +90,47 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, opts *Valida
 		if tx.GasLimit < intrGas {
 			return fmt.Errorf("%w: needed %v, allowed %v", ErrIntrinsicGas, intrGas, tx.GasLimit)
 		}
-		if tx.GasPrice.Price.Cmp(opts.MinTip) < 0 {
-			return fmt.Errorf("%w: tip needed %v, tip permitted %v", ErrUnderpriced, opts.MinTip, tx.GasPrice)
+		if tx.GasPrice.GasTipCapIntCmp(opts.MinTip) < 0 {
+			return fmt.Errorf("%w: tip needed %v, tip permitted %v", ErrUnderpriced, opts.MinTip, tx.GasPrice.GasTipCap())
+		}
+	}
+	if tx.Type() == types.RechargeTx {
+		// Recharges aren't signed, so nothing else ties the hash they're known by
+		// to their content
+		if hash := tx.SigHash(); hash != tx.TxHash {
+			return fmt.Errorf("%w: have %x, want %x", types.ErrInvalidTxHash, tx.TxHash, hash)
+		}
+		// Ensure no coin is spent twice by the same recharge
+		seen := make(map[state.CoinID]int, len(tx.InputCoins))
+		for i, input := range tx.InputCoins {
+			id := state.CoinID{TxHash: input.TxHash, Index: input.Index}
+			if j, ok := seen[id]; ok {
+				return fmt.Errorf("input %d: %w: as input %d", i, ErrDuplicateInput, j)
+			}
+			seen[id] = i
+		}
+		// Ensure every input coin is authorised by its witness and spendable in the
+		// next block
+		for i := range tx.InputCoins {
+			if err := types.VerifyWitness(tx, i, head.Number().Uint64()+1, uint64(time.Now().Unix())); err != nil {
+				return fmt.Errorf("input %d: %w", i, err)
+			}
 		}
 	}
+	return nil
+}
 
+// validateSender checks that the hash of a signed transaction matches its content
+// and that the signature was made by the From account for the pool's chain,
+// which the pool trusts from then on.
+func validateSender(signer types.Signer, tx *types.Transaction) error {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSender, err)
+	}
+	if from != tx.From {
+		return fmt.Errorf("%w: recovered %v, from %v", ErrSenderMismatch, from, tx.From)
+	}
 	return nil
 }
 
@@ -83,6 +145,11 @@ type ValidationOptionsWithState struct {
 	// nonce gaps will be ignored and permitted.
 	FirstNonceGap func(addr common.Address) uint64
 
+	// Funds is an optional callback to retrieve the balance an account has to pay
+	// for its pooled transactions, e.g. including value transferred to it by
+	// pending transactions. If it's not set, the balance in State is used.
+	Funds func(addr common.Address) *big.Int
+
 	// ExistingExpenditure is a mandatory callback to retrieve the cummulative
 	// cost of the already pooled transactions to check for overdrafts.
 	ExistingExpenditure func(addr common.Address, nonce uint64) *big.Int
@@ -98,7 +165,7 @@ type ValidationOptionsWithState struct {
 // This check is public to allow different transaction pools to check the stateful
 // rules without duplicating code and running the risk of missed updates.
 func ValidateTransactionWithState(tx *types.Transaction, opts *ValidationOptionsWithState) error {
-	if tx.Type() == types.NormalTx {
+	if tx.Type() == types.NormalTx || tx.Type() == types.WithdrawTx {
 		// Ensure the transaction adheres to nonce ordering
 		from := tx.From
 
@@ -118,6 +185,9 @@ func ValidateTransactionWithState(tx *types.Transaction, opts *ValidationOptions
 			balance = opts.State.GetBalance(from) // this balance dose not include txCosts
 			cost    = tx.Cost()
 		)
+		if opts.Funds != nil {
+			balance = opts.Funds(from)
+		}
 		if balance.Cmp(cost) < 0 {
 			return fmt.Errorf("%w: balance %v, tx cost %v, overshot %v", ErrInsufficientFunds, balance, cost, new(big.Int).Sub(cost, balance))
 		}
@@ -138,5 +208,19 @@ func ValidateTransactionWithState(tx *types.Transaction, opts *ValidationOptions
 			}
 		}
 	}
+	if tx.Type() == types.RechargeTx {
+		// Ensure every input coin is unspent and matches the coin set
+		for i, input := range tx.InputCoins {
+			coin := opts.State.GetCoin(state.CoinID{TxHash: input.TxHash, Index: input.Index})
+			switch {
+			case coin == nil:
+				return fmt.Errorf("input %d: %w: %x, %d", i, state.ErrCoinNotFound, input.TxHash, input.Index)
+			case coin.Spent():
+				return fmt.Errorf("input %d: %w: by %x", i, state.ErrCoinSpent, coin.SpentBy)
+			case input.Amount == nil || coin.Amount.Cmp(input.Amount) != 0 || !bytes.Equal(coin.Owner, input.Owner):
+				return fmt.Errorf("input %d: %w: amount %v, owner %x", i, ErrCoinMismatch, coin.Amount, coin.Owner)
+			}
+		}
+	}
 	return nil
 }
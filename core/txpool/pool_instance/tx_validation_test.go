@@ -0,0 +1,61 @@
+package txpool_instance
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types"
+	"execution/types/gadget"
+)
+
+// rechargeTransaction creates a recharge spending the given input coins, owned by
+// key, with witnesses signed by key and the hash of its content.
+func rechargeTransaction(inputs []gadget.InputCoin, key *ecdsa.PrivateKey) *types.Transaction {
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	witnesses := make([]gadget.Witness, len(inputs))
+	for i := range inputs {
+		inputs[i].Owner = owner.Bytes()
+	}
+	tx := types.NewRechargeTransaction(common.Hash{}, inputs, witnesses, gadget.NewGasPrice(big.NewInt(1)), owner)
+	for i := range tx.Witnesses {
+		tx.Witnesses[i].Sign(tx.WitnessHash(), key)
+	}
+	tx.TxHash = tx.SigHash()
+	return tx
+}
+
+// Tests that recharges are only valid under the hash of their content and
+// spending every input coin once.
+func TestValidateRecharge(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	var (
+		head = types.NewHeader(common.Hash{}, big.NewInt(0), 1000000)
+		opts = &ValidationOptions{Signer: testSigner, MaxSize: txMaxSize, MinTip: big.NewInt(1)}
+	)
+	tx := rechargeTransaction([]gadget.InputCoin{{TxHash: common.Hash{0x01}, Amount: big.NewInt(100)}}, key)
+	if err := ValidateTransaction(tx, head, opts); err != nil {
+		t.Fatalf("failed to validate recharge: %v", err)
+	}
+	tx.TxHash = common.Hash{0xff}
+	if err := ValidateTransaction(tx, head, opts); !errors.Is(err, types.ErrInvalidTxHash) {
+		t.Fatalf("mismatched hash error mismatch: have %v, want %v", err, types.ErrInvalidTxHash)
+	}
+	// Spending the same coin twice is refused, other coins of its withdrawal aren't
+	inputs := []gadget.InputCoin{
+		{TxHash: common.Hash{0x01}, Index: 0, Amount: big.NewInt(100)},
+		{TxHash: common.Hash{0x01}, Index: 1, Amount: big.NewInt(100)},
+	}
+	if err := ValidateTransaction(rechargeTransaction(inputs, key), head, opts); err != nil {
+		t.Fatalf("failed to validate recharge of distinct coins: %v", err)
+	}
+	inputs = append(inputs, gadget.InputCoin{TxHash: common.Hash{0x01}, Index: 0, Amount: big.NewInt(100)})
+	if err := ValidateTransaction(rechargeTransaction(inputs, key), head, opts); !errors.Is(err, ErrDuplicateInput) {
+		t.Fatalf("duplicate input error mismatch: have %v, want %v", err, ErrDuplicateInput)
+	}
+}
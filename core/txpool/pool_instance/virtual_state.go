@@ -17,75 +17,223 @@
 package txpool_instance
 
 import (
+	"math/big"
 	"sync"
 
 	"execution/common"
 	"execution/core/state"
+	"execution/types"
 )
 
-// Noncer is a tiny virtual state database to manage the executable nonces of
-// accounts in the pool, falling back to reading from a real state database if
-// an account is unknown.
-type Noncer struct {
-	fallback state.StateDB
+// VirtualState is a tiny virtual state database to manage the executable nonces
+// and spendable balances of accounts in the pool, with the pending transactions
+// applied on top of a real state database it falls back to reading from if an
+// account is unknown. Balances include the value transferred to an account by
+// pending transactions, which only holds if those execute first.
+type VirtualState struct {
+	fallback *state.StateDB
+	reads    *sync.Mutex // Guards the fallback, shared by all copies
+
 	nonces   map[common.Address]uint64
+	balances map[common.Address]*big.Int
+	spent    map[common.Address]*big.Int // Cost of the pending transactions of each sender
 	lock     sync.Mutex
 }
 
-// newNoncer creates a new virtual state database to track the pool nonces.
-func NewNoncer(statedb state.StateDB) *Noncer {
-	return &Noncer{
-		fallback: *statedb.Copy(),
+// NewVirtualState creates a new virtual state database on top of a copy of the
+// given state to track the pool nonces and balances.
+func NewVirtualState(statedb *state.StateDB) *VirtualState {
+	return &VirtualState{
+		fallback: statedb.Copy(),
+		reads:    new(sync.Mutex),
 		nonces:   make(map[common.Address]uint64),
+		balances: make(map[common.Address]*big.Int),
+		spent:    make(map[common.Address]*big.Int),
 	}
 }
 
-// get returns the current nonce of an account, falling back to a real state
-// database if the account is unknown.
-func (txn *Noncer) Get(addr common.Address) uint64 {
-	// We use mutex for get operation is the underlying
-	// state will mutate db even for read access.
-	txn.lock.Lock()
-	defer txn.lock.Unlock()
-
-	if _, ok := txn.nonces[addr]; !ok {
-		if nonce := txn.fallback.GetNonce(addr); nonce != 0 {
-			txn.nonces[addr] = nonce
+// Copy returns an independent copy of the virtual state, sharing the read-only
+// fallback, e.g. to apply transactions speculatively while building a block.
+func (vs *VirtualState) Copy() *VirtualState {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	cpy := &VirtualState{
+		fallback: vs.fallback,
+		reads:    vs.reads,
+		nonces:   make(map[common.Address]uint64, len(vs.nonces)),
+		balances: make(map[common.Address]*big.Int, len(vs.balances)),
+		spent:    make(map[common.Address]*big.Int, len(vs.spent)),
+	}
+	for addr, nonce := range vs.nonces {
+		cpy.nonces[addr] = nonce
+	}
+	for addr, balance := range vs.balances {
+		cpy.balances[addr] = new(big.Int).Set(balance)
+	}
+	for addr, spent := range vs.spent {
+		cpy.spent[addr] = new(big.Int).Set(spent)
+	}
+	return cpy
+}
+
+// nonce returns the current nonce of an account, loading it from the fallback
+// if the account is unknown. The lock must be held.
+func (vs *VirtualState) nonce(addr common.Address) uint64 {
+	if _, ok := vs.nonces[addr]; !ok {
+		// We use mutex for reading the fallback as the underlying state will
+		// mutate db even for read access.
+		vs.reads.Lock()
+		nonce := vs.fallback.GetNonce(addr)
+		vs.reads.Unlock()
+
+		if nonce != 0 {
+			vs.nonces[addr] = nonce
 		}
 	}
-	return txn.nonces[addr]
+	return vs.nonces[addr]
 }
 
-// set inserts a new virtual nonce into the virtual state database to be returned
-// whenever the pool requests it instead of reaching into the real state database.
-func (txn *Noncer) Set(addr common.Address, nonce uint64) {
-	txn.lock.Lock()
-	defer txn.lock.Unlock()
+// balance returns the current balance of an account, loading it from the
+// fallback if the account is unknown. The lock must be held.
+func (vs *VirtualState) balance(addr common.Address) *big.Int {
+	balance, ok := vs.balances[addr]
+	if !ok {
+		vs.reads.Lock()
+		balance = new(big.Int).Set(vs.fallback.GetBalance(addr))
+		vs.reads.Unlock()
 
-	txn.nonces[addr] = nonce
+		vs.balances[addr] = balance
+	}
+	return balance
+}
+
+// GetNonce returns the current nonce of an account, falling back to a real
+// state database if the account is unknown.
+func (vs *VirtualState) GetNonce(addr common.Address) uint64 {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	return vs.nonce(addr)
+}
+
+// SetNonce inserts a new virtual nonce into the virtual state database to be
+// returned whenever the pool requests it instead of reaching into the real state
+// database.
+func (vs *VirtualState) SetNonce(addr common.Address, nonce uint64) {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	vs.nonces[addr] = nonce
+}
+
+// SetNonceIfLower updates a new virtual nonce into the virtual state database if
+// the new one is lower.
+func (vs *VirtualState) SetNonceIfLower(addr common.Address, nonce uint64) {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	if vs.nonce(addr) <= nonce {
+		return
+	}
+	vs.nonces[addr] = nonce
 }
 
-// setIfLower updates a new virtual nonce into the virtual state database if the
-// new one is lower.
-func (txn *Noncer) SetIfLower(addr common.Address, nonce uint64) {
-	txn.lock.Lock()
-	defer txn.lock.Unlock()
+// GetBalance returns the balance an account has left to spend with its pending
+// transactions applied, falling back to a real state database if the account
+// is unknown. Accounts overdrawn by their pending transactions have nothing left.
+func (vs *VirtualState) GetBalance(addr common.Address) *big.Int {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
 
-	if _, ok := txn.nonces[addr]; !ok {
-		if nonce := txn.fallback.GetNonce(addr); nonce != 0 {
-			txn.nonces[addr] = nonce
+	if balance := vs.balance(addr); balance.Sign() > 0 {
+		return new(big.Int).Set(balance)
+	}
+	return new(big.Int)
+}
+
+// GetFunds returns the balance an account has to pay for its pending
+// transactions: its balance in the real state database plus the value pending
+// transactions transfer to it, before any of its own pending transactions are
+// charged. Cumulative costs of an account's pending transactions are checked
+// against it, while GetBalance is what's left beyond them.
+func (vs *VirtualState) GetFunds(addr common.Address) *big.Int {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	funds := new(big.Int).Set(vs.balance(addr))
+	if spent := vs.spent[addr]; spent != nil {
+		funds.Add(funds, spent)
+	}
+	if funds.Sign() < 0 {
+		return new(big.Int)
+	}
+	return funds
+}
+
+// charged reports whether a transaction's cost is paid from its sender's
+// balance, which holds for every transaction with a sender.
+func charged(tx *types.Transaction) bool {
+	switch tx.Type() {
+	case types.NormalTx, types.WithdrawTx:
+		return true
+	}
+	return false
+}
+
+// Apply applies a transaction on top of the virtual state: it moves the nonce
+// of the sender past it, charges its cost to the sender and credits its value
+// to the recipient. The output coins of withdrawals are part of their cost.
+func (vs *VirtualState) Apply(tx *types.Transaction) {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	vs.nonces[tx.From] = tx.Nonce + 1
+	if charged(tx) {
+		cost := tx.Cost()
+		vs.balance(tx.From).Sub(vs.balance(tx.From), cost)
+		if spent := vs.spent[tx.From]; spent != nil {
+			spent.Add(spent, cost)
+		} else {
+			vs.spent[tx.From] = cost
 		}
 	}
-	if txn.nonces[addr] <= nonce {
-		return
+	if tx.Type() == types.NormalTx && tx.Value != nil && tx.To != (common.Address{}) {
+		vs.balance(tx.To).Add(vs.balance(tx.To), tx.Value)
+	}
+}
+
+// Unapply reverts a transaction applied on top of the virtual state: it refunds
+// its cost to the sender, takes its value back from the recipient and lowers
+// the nonce of the sender to the transaction's if it was higher.
+func (vs *VirtualState) Unapply(tx *types.Transaction) {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+
+	if vs.nonce(tx.From) > tx.Nonce {
+		vs.nonces[tx.From] = tx.Nonce
+	}
+	if charged(tx) {
+		cost := tx.Cost()
+		vs.balance(tx.From).Add(vs.balance(tx.From), cost)
+		if spent := vs.spent[tx.From]; spent != nil {
+			if spent.Sub(spent, cost); spent.Sign() <= 0 {
+				delete(vs.spent, tx.From)
+			}
+		}
+	}
+	if tx.Type() == types.NormalTx && tx.Value != nil && tx.To != (common.Address{}) {
+		vs.balance(tx.To).Sub(vs.balance(tx.To), tx.Value)
 	}
-	txn.nonces[addr] = nonce
 }
 
-// setAll sets the nonces for all accounts to the given map.
-func (txn *Noncer) SetAll(all map[common.Address]uint64) {
-	txn.lock.Lock()
-	defer txn.lock.Unlock()
+// Clear drops all virtual nonces and balances, reverting to the real state
+// database.
+func (vs *VirtualState) Clear() {
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
 
-	txn.nonces = all
+	vs.nonces = make(map[common.Address]uint64)
+	vs.balances = make(map[common.Address]*big.Int)
+	vs.spent = make(map[common.Address]*big.Int)
 }
@@ -0,0 +1,117 @@
+package txpool_instance
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/crypto"
+	"execution/types"
+	"execution/types/gadget"
+)
+
+// Tests that the pending state charges pending transactions to their senders and
+// credits their value to their recipients, that copies of it are independent and
+// that dropping a transaction reverts it.
+func TestPendingStateBalances(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	recvKey, _ := crypto.GenerateKey()
+	var (
+		from  = crypto.PubkeyToAddress(key.PublicKey)
+		recv  = crypto.PubkeyToAddress(recvKey.PublicKey)
+		value = big.NewInt(1000)
+	)
+	testAddBalance(pool, from, big.NewInt(1000000))
+
+	tx := types.NewNormalTransaction(0, recv, value, 100000, gadget.NewGasPrice(big.NewInt(1)), nil, testSigner, key)
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	state := pool.PendingState()
+	if nonce := state.GetNonce(from); nonce != 1 {
+		t.Fatalf("pending nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if balance, want := state.GetBalance(from), new(big.Int).Sub(big.NewInt(1000000), tx.Cost()); balance.Cmp(want) != 0 {
+		t.Fatalf("sender balance mismatch: have %v, want %v", balance, want)
+	}
+	if balance := state.GetBalance(recv); balance.Cmp(value) != 0 {
+		t.Fatalf("recipient balance mismatch: have %v, want %v", balance, value)
+	}
+	// Spend the incoming value speculatively on a copy
+	spend := types.NewNormalTransaction(0, from, value, 0, gadget.NewGasPrice(big.NewInt(0)), nil, testSigner, recvKey)
+	state.Apply(spend)
+	if balance := state.GetBalance(recv); balance.Sign() != 0 {
+		t.Fatalf("speculative recipient balance mismatch: have %v, want 0", balance)
+	}
+	if balance := pool.PendingState().GetBalance(recv); balance.Cmp(value) != 0 {
+		t.Fatalf("pool recipient balance changed by copy: have %v, want %v", balance, value)
+	}
+	// Withdrawals charge their output coins to the sender as well
+	withdraw := types.NewWithdrawTransaction(1, gadget.NewGasPrice(big.NewInt(1)), []gadget.OutputCoin{{Amount: big.NewInt(5000), Owner: recv}}, testSigner, key)
+	before := state.GetBalance(from)
+	state.Apply(withdraw)
+	if balance, want := state.GetBalance(from), new(big.Int).Sub(before, withdraw.Cost()); balance.Cmp(want) != 0 {
+		t.Fatalf("withdrawing sender balance mismatch: have %v, want %v", balance, want)
+	}
+	state.Unapply(withdraw)
+	if balance := state.GetBalance(from); balance.Cmp(before) != 0 {
+		t.Fatalf("reverted withdrawing sender balance mismatch: have %v, want %v", balance, before)
+	}
+	// Drop the transaction and check that it's reverted
+	pool.mu.Lock()
+	pool.removeTx(tx.TxHash, true)
+	pool.mu.Unlock()
+
+	state = pool.PendingState()
+	if nonce := state.GetNonce(from); nonce != 0 {
+		t.Fatalf("reverted nonce mismatch: have %d, want %d", nonce, 0)
+	}
+	if balance := state.GetBalance(from); balance.Cmp(big.NewInt(1000000)) != 0 {
+		t.Fatalf("reverted sender balance mismatch: have %v, want %v", balance, 1000000)
+	}
+	if balance := state.GetBalance(recv); balance.Sign() != 0 {
+		t.Fatalf("reverted recipient balance mismatch: have %v, want 0", balance)
+	}
+}
+
+// Tests that the pool lets accounts spend the value pending transactions send
+// them, and only what's left beyond their pending transactions on queued ones.
+func TestPendingStateFunds(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	recvKey, _ := crypto.GenerateKey()
+	var (
+		from = crypto.PubkeyToAddress(key.PublicKey)
+		recv = crypto.PubkeyToAddress(recvKey.PublicKey)
+	)
+	testAddBalance(pool, from, big.NewInt(1000000))
+
+	// The recipient has no funds of its own, only those sent to it
+	fund := types.NewNormalTransaction(0, recv, big.NewInt(200000), 100000, gadget.NewGasPrice(big.NewInt(1)), nil, testSigner, key)
+	if err := pool.addRemoteSync(fund); err != nil {
+		t.Fatalf("failed to add funding transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(0, 100000, recvKey)); err != nil {
+		t.Fatalf("failed to spend pending funds: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(1, 100000, recvKey)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("overspending error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	if funds := pool.PendingState().GetFunds(recv); funds.Cmp(big.NewInt(200000)) != 0 {
+		t.Fatalf("recipient funds mismatch: have %v, want %v", funds, 200000)
+	}
+	pending, queued := count(t, pool)
+	if pending != 2 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d pending %d queued, want 2 pending 0 queued", pending, queued)
+	}
+	if txs := pool.Pending()[recv]; len(txs) != 1 {
+		t.Fatalf("recipient pending transactions mismatch: have %d, want %d", len(txs), 1)
+	}
+}
@@ -3,7 +3,7 @@ package txpool
 import (
 	"execution/common"
 	instance "execution/core/txpool/pool_instance"
-	"execution/core/types"
+	"execution/types"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/event"
@@ -58,10 +58,18 @@ type SubPool interface {
 	// to a later point to batch multiple ones together.
 	Add(txs []*Transaction, local bool, sync bool) []error
 
+	// AddFromPeer enqueues a batch of remote transactions relayed by the given
+	// peer like Add does, subject to the per-peer rate limit on top.
+	AddFromPeer(txs []*Transaction, peer string, sync bool) []error
+
 	// Pending retrieves all currently processable transactions, grouped by origin
 	// account and sorted by nonce.
 	Pending(enforceTips bool) map[common.Address][]*types.Transaction
 
+	// PendingParallel packs the currently processable transactions into a block
+	// of parallel lanes, taking out of the subpool only the packed ones.
+	PendingParallel(enforceTips bool, baseFee *big.Int, gasLimit uint64, lanes int) *instance.PackedBlock
+
 	// SubscribeTransactions subscribes to new transaction events.
 	SubscribeTransactions(ch chan<- instance.NewTxsEvent) event.Subscription
 
@@ -81,6 +89,18 @@ type SubPool interface {
 	// pending as well as queued transactions of this address, grouped by nonce.
 	ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction)
 
+	// Inspect returns a diagnostic snapshot of every account with transactions
+	// in the pool.
+	Inspect() map[common.Address]*instance.AccountStatus
+
+	// LaneStats returns a snapshot of the pool capacity used by every priority
+	// lane of the subpool.
+	LaneStats() map[string]*instance.LaneStatus
+
+	// Ordering returns the policy the subpool orders transactions across
+	// accounts by.
+	Ordering() instance.OrderingPolicy
+
 	// Locals retrieves the accounts currently considered local by the pool.
 	Locals() []common.Address
 
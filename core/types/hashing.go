@@ -38,6 +38,12 @@ var encodeBufferPool = sync.Pool{
 	New: func() interface{} { return new(bytes.Buffer) },
 }
 
+// RlpHash encodes x and hashes the encoded bytes. It lets other packages hash
+// their consensus objects the same way as the ones of this package.
+func RlpHash(x interface{}) common.Hash {
+	return rlpHash(x)
+}
+
 // rlpHash encodes x and hashes the encoded bytes.
 func rlpHash(x interface{}) (h common.Hash) {
 	sha := hasherPool.Get().(crypto.KeccakState)
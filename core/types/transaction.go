@@ -46,6 +46,7 @@ const (
 	AccessListTxType = 0x01
 	DynamicFeeTxType = 0x02
 	BlobTxType       = 0x03
+	BHTxType         = 0x40
 )
 
 // Transaction is an Ethereum transaction.
@@ -68,7 +69,7 @@ func NewTx(inner TxData) *Transaction {
 
 // TxData is the underlying data of a transaction.
 //
-// This is implemented by DynamicFeeTx, LegacyTx and AccessListTx.
+// This is implemented by DynamicFeeTx, LegacyTx, AccessListTx and BHTx.
 type TxData interface {
 	txType() byte // returns the type ID
 	copy() TxData // creates a deep copy and initializes all fields
@@ -200,6 +201,10 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner BlobTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case BHTxType:
+		var inner BHTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -394,14 +399,19 @@ func (tx *Transaction) BlobGasFeeCapIntCmp(other *big.Int) int {
 	return tx.inner.blobGasFeeCap().Cmp(other)
 }
 
-// Hash returns the transaction hash.
+// Hash returns the transaction hash. BHTx transactions keep the hash of their
+// own signer, so they are known by the same hash in the pool and in blocks.
+// That hash is taken as is here, converting the envelope back with the
+// FromEnvelope of the transaction package checks it against the content.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
 
 	var h common.Hash
-	if tx.Type() == LegacyTxType {
+	if inner, ok := tx.inner.(*BHTx); ok {
+		h = inner.TxHash
+	} else if tx.Type() == LegacyTxType {
 		h = rlpHash(tx.inner)
 	} else {
 		h = prefixedRlpHash(tx.Type(), tx.inner)
@@ -1 +1,191 @@
 package types
+
+import (
+	"math/big"
+
+	"execution/common"
+	"execution/types/gadget"
+)
+
+// BHTx is the transaction data of the coin carrying transactions of this chain:
+// normal, withdraw and recharge transactions. Its fields follow the canonical
+// binary layout of those transactions, with the signature split into V, R and S,
+// so they convert to and from this envelope without loss.
+//
+// Unlike other transaction types, a BHTx is identified by the hash its own
+// signer committed to, which it carries along.
+//
+// BHTx is a transport format, not a second transaction model. The canonical
+// type of these transactions is the Transaction of the execution/types package,
+// which converts to and from a BHTx with Envelope and FromEnvelope.
+type BHTx struct {
+	TxHash           common.Hash
+	From             common.Address
+	Nonce            uint64
+	Gas              uint64
+	GasPrice         []*big.Int // [price] for legacy pricing, [fee cap, tip cap] for dynamic fees
+	Value            *big.Int
+	InputCoins       []gadget.InputCoin
+	Witnesses        []gadget.Witness
+	OutputCoins      []gadget.OutputCoin
+	To               common.Address // zero means contract creation
+	Data             []byte
+	AccessList       *gadget.AccessList `rlp:"nil"`
+	Refund           *gadget.Refund     `rlp:"nil"`
+	Extend           []byte
+	StrictAccessList *gadget.AccessList `rlp:"nil"`
+	ValidUntil       *gadget.Deadline   `rlp:"nil"`
+
+	// Signature values, zero for recharge transactions
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *BHTx) copy() TxData {
+	cpy := &BHTx{
+		TxHash:      tx.TxHash,
+		From:        tx.From,
+		Nonce:       tx.Nonce,
+		Gas:         tx.Gas,
+		GasPrice:    make([]*big.Int, len(tx.GasPrice)),
+		InputCoins:  make([]gadget.InputCoin, len(tx.InputCoins)),
+		Witnesses:   make([]gadget.Witness, len(tx.Witnesses)),
+		OutputCoins: make([]gadget.OutputCoin, len(tx.OutputCoins)),
+		To:          tx.To,
+		Data:        common.CopyBytes(tx.Data),
+		Extend:      common.CopyBytes(tx.Extend),
+		// These are copied below.
+		Value: new(big.Int),
+		V:     new(big.Int),
+		R:     new(big.Int),
+		S:     new(big.Int),
+	}
+	for i, price := range tx.GasPrice {
+		cpy.GasPrice[i] = new(big.Int).Set(price)
+	}
+	copy(cpy.InputCoins, tx.InputCoins)
+	copy(cpy.Witnesses, tx.Witnesses)
+	copy(cpy.OutputCoins, tx.OutputCoins)
+
+	if tx.AccessList != nil {
+		list := *tx.AccessList
+		cpy.AccessList = &list
+	}
+	if tx.Refund != nil {
+		refund := *tx.Refund
+		cpy.Refund = &refund
+	}
+	if tx.StrictAccessList != nil {
+		list := *tx.StrictAccessList
+		cpy.StrictAccessList = &list
+	}
+	if tx.ValidUntil != nil {
+		deadline := *tx.ValidUntil
+		cpy.ValidUntil = &deadline
+	}
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *BHTx) txType() byte              { return BHTxType }
+func (tx *BHTx) data() []byte              { return tx.Data }
+func (tx *BHTx) gas() uint64               { return tx.Gas }
+func (tx *BHTx) gasFeeCap() *big.Int       { return tx.feeCap() }
+func (tx *BHTx) gasTipCap() *big.Int       { return tx.tipCap() }
+func (tx *BHTx) gasPrice() *big.Int        { return tx.feeCap() }
+func (tx *BHTx) value() *big.Int           { return tx.Value }
+func (tx *BHTx) nonce() uint64             { return tx.Nonce }
+func (tx *BHTx) blobGas() uint64           { return 0 }
+func (tx *BHTx) blobGasFeeCap() *big.Int   { return nil }
+func (tx *BHTx) blobHashes() []common.Hash { return nil }
+
+// chainID derives the chain ID from V, the signature is the only place it is
+// kept in. Unprotected and unsigned transactions return zero.
+func (tx *BHTx) chainID() *big.Int {
+	if tx.V == nil || tx.V.Sign() == 0 {
+		return new(big.Int)
+	}
+	return deriveChainId(tx.V)
+}
+
+// accessList flattens the declared reads and writes into a single list.
+func (tx *BHTx) accessList() AccessList {
+	var list AccessList
+	for _, al := range []*gadget.AccessList{tx.AccessList, tx.StrictAccessList} {
+		if al == nil {
+			continue
+		}
+		for _, tuples := range [][]gadget.AccessTuple{al.Reads, al.Writes} {
+			for _, tuple := range tuples {
+				list = append(list, AccessTuple{Address: tuple.Address, StorageKeys: tuple.StorageKeys})
+			}
+		}
+	}
+	return list
+}
+
+func (tx *BHTx) to() *common.Address {
+	if tx.To == (common.Address{}) {
+		return nil
+	}
+	to := tx.To
+	return &to
+}
+
+// feeCap and tipCap unpack the canonical gas price list, see GasPrice.
+func (tx *BHTx) feeCap() *big.Int {
+	if len(tx.GasPrice) == 0 {
+		return new(big.Int)
+	}
+	return tx.GasPrice[0]
+}
+
+func (tx *BHTx) tipCap() *big.Int {
+	if len(tx.GasPrice) < 2 {
+		return tx.feeCap()
+	}
+	return tx.GasPrice[1]
+}
+
+func (tx *BHTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return dst.Set(tx.feeCap())
+	}
+	tip := dst.Sub(tx.feeCap(), baseFee)
+	if tip.Cmp(tx.tipCap()) > 0 {
+		tip.Set(tx.tipCap())
+	}
+	return tip.Add(tip, baseFee)
+}
+
+func (tx *BHTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *BHTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.V, tx.R, tx.S = v, r, s
+}
+
+// BHTx returns a copy of the inner data of a BHTx transaction, false for other
+// transaction types.
+func (tx *Transaction) BHTx() (*BHTx, bool) {
+	inner, ok := tx.inner.(*BHTx)
+	if !ok {
+		return nil, false
+	}
+	return inner.copy().(*BHTx), true
+}
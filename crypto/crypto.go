@@ -50,6 +50,13 @@ var (
 	secp256k1halfN = new(big.Int).Div(secp256k1N, big.NewInt(2))
 )
 
+// Secp256k1N and Secp256k1halfN export the curve order and its half for the
+// signature checks of other packages. They must not be modified.
+var (
+	Secp256k1N     = secp256k1N
+	Secp256k1halfN = secp256k1halfN
+)
+
 var errInvalidPubkey = errors.New("invalid secp256k1 public key")
 
 // KeccakState wraps sha3.state. In addition to the usual hash methods, it also supports
@@ -137,3 +137,25 @@ func TestConfigRules(t *testing.T) {
 		t.Errorf("expected %v to be shanghai", stamp)
 	}
 }
+
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{InitialBaseFee, 20000000, 10000000, InitialBaseFee}, // usage == target
+		{InitialBaseFee, 20000000, 9000000, 987500000},       // usage below target
+		{InitialBaseFee, 20000000, 11000000, 1012500000},     // usage above target
+	}
+	for i, test := range tests {
+		have := CalcBaseFee(nil, big.NewInt(test.parentBaseFee), test.parentGasUsed, test.parentGasLimit)
+		if have, want := have, big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+	if have := CalcBaseFee(nil, nil, 0, 20000000); have.Cmp(big.NewInt(InitialBaseFee)) != 0 {
+		t.Errorf("missing parent base fee: have %d, want %d", have, InitialBaseFee)
+	}
+}
@@ -0,0 +1,43 @@
+package params
+
+import "math/big"
+
+// CalcBaseFee calculates the base fee of a block from the base fee, gas used
+// and gas limit of its parent. If the parent has no base fee, dynamic pricing
+// starts out at InitialBaseFee.
+func CalcBaseFee(config *ChainConfig, parentBaseFee *big.Int, parentGasUsed, parentGasLimit uint64) *big.Int {
+	if parentBaseFee == nil {
+		return new(big.Int).SetUint64(InitialBaseFee)
+	}
+	parentGasTarget := parentGasLimit / config.ElasticityMultiplier()
+	// If the parent gasUsed is the same as the target, the baseFee remains unchanged.
+	if parentGasUsed == parentGasTarget || parentGasTarget == 0 {
+		return new(big.Int).Set(parentBaseFee)
+	}
+	var num, denom = new(big.Int), new(big.Int)
+
+	if parentGasUsed > parentGasTarget {
+		// If the parent block used more gas than its target, the baseFee should increase.
+		// max(1, parentBaseFee * gasUsedDelta / parentGasTarget / baseFeeChangeDenominator)
+		num.SetUint64(parentGasUsed - parentGasTarget)
+		num.Mul(num, parentBaseFee)
+		num.Div(num, denom.SetUint64(parentGasTarget))
+		num.Div(num, denom.SetUint64(config.BaseFeeChangeDenominator()))
+		if num.Sign() == 0 {
+			num.SetUint64(1)
+		}
+		return num.Add(num, parentBaseFee)
+	}
+	// Otherwise if the parent block used less gas than its target, the baseFee should decrease.
+	// max(0, parentBaseFee * gasUsedDelta / parentGasTarget / baseFeeChangeDenominator)
+	num.SetUint64(parentGasTarget - parentGasUsed)
+	num.Mul(num, parentBaseFee)
+	num.Div(num, denom.SetUint64(parentGasTarget))
+	num.Div(num, denom.SetUint64(config.BaseFeeChangeDenominator()))
+
+	baseFee := num.Sub(parentBaseFee, num)
+	if baseFee.Sign() < 0 {
+		baseFee.SetUint64(0)
+	}
+	return baseFee
+}
@@ -475,6 +475,10 @@ func (op ptrOp) genDecode(ctx *genContext) (string, string) {
 	fmt.Fprintf(&b, "} else if %s != 0 || %s != %s {\n", sizeV, kindV, wantKind)
 	fmt.Fprint(&b, code)
 	fmt.Fprintf(&b, "  %s = &%s\n", resultV, result)
+	// The empty value of a nil pointer still has to be read past, Kind only
+	// peeks at it.
+	fmt.Fprintf(&b, "} else if _, err := dec.Raw(); err != nil {\n")
+	fmt.Fprintf(&b, "  return err\n")
 	fmt.Fprintf(&b, "}\n")
 	return resultV, b.String()
 }
@@ -650,7 +654,13 @@ func (op sliceOp) genWrite(ctx *genContext, v string) string {
 
 	var b bytes.Buffer
 	fmt.Fprintf(&b, "%s := w.List()\n", listMarker)
-	fmt.Fprintf(&b, "for _, %s := range %s {\n", iterElemV, v)
+	if elem, ok := op.elemOp.(structOp); ok && len(elem.fields) == 0 && len(elem.optionalFields) == 0 {
+		// Elements without fields are written without reading the iteration
+		// variable, which must not be declared then.
+		fmt.Fprintf(&b, "for range %s {\n", v)
+	} else {
+		fmt.Fprintf(&b, "for _, %s := range %s {\n", iterElemV, v)
+	}
 	fmt.Fprint(&b, elemCode)
 	fmt.Fprintf(&b, "}\n")
 	fmt.Fprintf(&b, "w.ListEnd(%s)\n", listMarker)
@@ -21,10 +21,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"go/build"
+	"go/importer"
+	"go/token"
 	"go/types"
 	"os"
-
-	"golang.org/x/tools/go/packages"
 )
 
 const pathOfPackageRLP = "execution/rlp"
@@ -71,35 +72,19 @@ type Config struct {
 
 // process generates the Go code.
 func (cfg *Config) process() (code []byte, err error) {
-	// Load packages.
-	pcfg := &packages.Config{
-		Mode:       packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
-		Dir:        cfg.Dir,
-		BuildFlags: []string{"-tags", "norlpgen"},
-	}
-	ps, err := packages.Load(pcfg, pathOfPackageRLP, ".")
+	// Load packages from source, leaving out the generated code which may be
+	// stale. The source importer is used over go/packages, whose type checker
+	// breaks on newer toolchains.
+	build.Default.BuildTags = append(build.Default.BuildTags, "norlpgen")
+	imp := importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+
+	packageRLP, err := imp.ImportFrom(pathOfPackageRLP, cfg.Dir, 0)
 	if err != nil {
-		return nil, err
-	}
-	if len(ps) == 0 {
-		return nil, fmt.Errorf("no Go package found in %s", cfg.Dir)
+		return nil, fmt.Errorf("can't load package RLP: %v", err)
 	}
-	packages.PrintErrors(ps)
-
-	// Find the packages that were loaded.
-	var (
-		pkg        *types.Package
-		packageRLP *types.Package
-	)
-	for _, p := range ps {
-		if len(p.Errors) > 0 {
-			return nil, fmt.Errorf("package %s has errors", p.PkgPath)
-		}
-		if p.PkgPath == pathOfPackageRLP {
-			packageRLP = p.Types
-		} else {
-			pkg = p.Types
-		}
+	pkg, err := imp.ImportFrom(".", cfg.Dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("can't load package in %s: %v", cfg.Dir, err)
 	}
 	bctx := newBuildContext(packageRLP)
 
@@ -1,34 +1,95 @@
 package types
 
 import (
-	"execution/common"
+	"io"
 	"math/big"
+
+	"execution/common"
+	ethtypes "execution/core/types"
+	"execution/rlp"
 )
 
+// Header is the consensus header of a block. Its hash is computed over its RLP
+// encoding, so a header commits to the body by the tx and withdrawal roots and
+// to the execution results by the state and receipt roots.
 type Header struct {
-	hash       common.Hash
-	parentHash common.Hash
-	number     *big.Int
-	gasLimit   uint64
+	parentHash      common.Hash
+	coinbase        common.Address // Proposer of the block, receiving its fees
+	root            common.Hash    // State root after executing the block
+	txHash          common.Hash    // Root of the transactions of the body, see Transactions.Root
+	receiptHash     common.Hash    // Root of the receipts of the block, see ReceiptsRoot
+	withdrawalsRoot common.Hash    // Root of the withdrawal records of the block
+	number          *big.Int
+	gasLimit        uint64
+	gasUsed         uint64
+	time            uint64
+	extra           []byte
+	baseFee         *big.Int // nil if the chain doesn't price gas dynamically
+}
+
+// headerRLP is the consensus encoding of a header.
+type headerRLP struct {
+	ParentHash      common.Hash
+	Coinbase        common.Address
+	Root            common.Hash
+	TxHash          common.Hash
+	ReceiptHash     common.Hash
+	WithdrawalsRoot common.Hash
+	Number          *big.Int
+	GasLimit        uint64
+	GasUsed         uint64
+	Time            uint64
+	Extra           []byte
+	BaseFee         *big.Int `rlp:"optional"`
+}
+
+// NewHeader creates a header on top of the given parent. The remaining fields
+// are filled in by the With methods while the block is built.
+func NewHeader(parentHash common.Hash, number *big.Int, gasLimit uint64) *Header {
+	return &Header{
+		parentHash: parentHash,
+		number:     number,
+		gasLimit:   gasLimit,
+	}
 }
 
-func NewHeader(hash common.Hash, parentHash common.Hash, number *big.Int, gasLimit uint64) *Header {
+// NewHeaderWithBaseFee creates a header for a chain pricing gas with a dynamic
+// base fee, carrying the gas used by the block to derive the next base fee.
+func NewHeaderWithBaseFee(parentHash common.Hash, number *big.Int, gasLimit uint64, gasUsed uint64, baseFee *big.Int) *Header {
 	return &Header{
-		hash:       hash,
 		parentHash: parentHash,
 		number:     number,
 		gasLimit:   gasLimit,
+		gasUsed:    gasUsed,
+		baseFee:    baseFee,
 	}
 }
 
+// Hash returns the keccak256 hash of the RLP encoding of the header.
 func (header *Header) Hash() common.Hash {
-	return header.hash
+	return ethtypes.RlpHash(header)
 }
 
 func (header *Header) ParentHash() common.Hash {
 	return header.parentHash
 }
 
+func (header *Header) Coinbase() common.Address {
+	return header.coinbase
+}
+
+func (header *Header) Root() common.Hash {
+	return header.root
+}
+
+func (header *Header) TxHash() common.Hash {
+	return header.txHash
+}
+
+func (header *Header) ReceiptHash() common.Hash {
+	return header.receiptHash
+}
+
 func (header *Header) Number() *big.Int {
 	return header.number
 }
@@ -37,6 +98,105 @@ func (header *Header) GasLimit() uint64 {
 	return header.gasLimit
 }
 
+func (header *Header) GasUsed() uint64 {
+	return header.gasUsed
+}
+
+func (header *Header) Time() uint64 {
+	return header.time
+}
+
+func (header *Header) Extra() []byte {
+	return common.CopyBytes(header.extra)
+}
+
+func (header *Header) BaseFee() *big.Int {
+	return header.baseFee
+}
+
+// WithdrawalsRoot returns the root of the withdrawal records of the block, see
+// Withdrawals.Root. It is the zero hash for blocks without withdrawals.
+func (header *Header) WithdrawalsRoot() common.Hash {
+	return header.withdrawalsRoot
+}
+
+// WithWithdrawalsRoot returns a copy of the header with the withdrawal root set.
+func (header *Header) WithWithdrawalsRoot(root common.Hash) *Header {
+	cpy := *header
+	cpy.withdrawalsRoot = root
+	return &cpy
+}
+
+// WithProposal returns a copy of the header with the fields chosen by the
+// proposer of the block set.
+func (header *Header) WithProposal(coinbase common.Address, time uint64, extra []byte) *Header {
+	cpy := *header
+	cpy.coinbase = coinbase
+	cpy.time = time
+	cpy.extra = common.CopyBytes(extra)
+	return &cpy
+}
+
+// WithBody returns a copy of the header committing to the given body by its tx
+// and withdrawal roots.
+func (header *Header) WithBody(body *Body) *Header {
+	cpy := *header
+	cpy.txHash = body.Transactions().Root()
+	cpy.withdrawalsRoot = TxWithdrawals(body.Transactions()).Root()
+	return &cpy
+}
+
+// WithExecution returns a copy of the header with the results of executing the
+// block set.
+func (header *Header) WithExecution(root common.Hash, receiptHash common.Hash, gasUsed uint64) *Header {
+	cpy := *header
+	cpy.root = root
+	cpy.receiptHash = receiptHash
+	cpy.gasUsed = gasUsed
+	return &cpy
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (header *Header) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &headerRLP{
+		ParentHash:      header.parentHash,
+		Coinbase:        header.coinbase,
+		Root:            header.root,
+		TxHash:          header.txHash,
+		ReceiptHash:     header.receiptHash,
+		WithdrawalsRoot: header.withdrawalsRoot,
+		Number:          header.number,
+		GasLimit:        header.gasLimit,
+		GasUsed:         header.gasUsed,
+		Time:            header.time,
+		Extra:           header.extra,
+		BaseFee:         header.baseFee,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (header *Header) DecodeRLP(s *rlp.Stream) error {
+	var dec headerRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*header = Header{
+		parentHash:      dec.ParentHash,
+		coinbase:        dec.Coinbase,
+		root:            dec.Root,
+		txHash:          dec.TxHash,
+		receiptHash:     dec.ReceiptHash,
+		withdrawalsRoot: dec.WithdrawalsRoot,
+		number:          dec.Number,
+		gasLimit:        dec.GasLimit,
+		gasUsed:         dec.GasUsed,
+		time:            dec.Time,
+		extra:           dec.Extra,
+		baseFee:         dec.BaseFee,
+	}
+	return nil
+}
+
 type Body struct {
 	transactions Transactions
 }
@@ -63,6 +223,32 @@ func NewBlock(header *Header, body *Body) *Block {
 	}
 }
 
+// blockRLP is the encoding of a block, its header followed by the transactions
+// of its body.
+type blockRLP struct {
+	Header *Header
+	Txs    Transactions
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (block *Block) EncodeRLP(w io.Writer) error {
+	enc := &blockRLP{Header: block.header}
+	if block.body != nil {
+		enc.Txs = block.body.transactions
+	}
+	return rlp.Encode(w, enc)
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (block *Block) DecodeRLP(s *rlp.Stream) error {
+	var dec blockRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	block.header, block.body = dec.Header, NewBody(dec.Txs)
+	return nil
+}
+
 func (block *Block) Header() *Header {
 	return block.header
 }
@@ -82,3 +268,16 @@ func (block *Block) NumberU64() uint64 {
 func (block *Block) Transactions() Transactions {
 	return block.body.Transactions()
 }
+
+// Withdrawals returns the withdrawal records of the withdraw transactions of the
+// block.
+func (block *Block) Withdrawals() Withdrawals {
+	return TxWithdrawals(block.Transactions())
+}
+
+// WithdrawalProof returns the record of an output of a withdraw transaction of
+// the block along with a proof of its inclusion under the header's withdrawal
+// root, for bridges to verify with VerifyWithdrawal.
+func (block *Block) WithdrawalProof(txHash common.Hash, index uint32) (*Withdrawal, *WithdrawalProof, error) {
+	return block.Withdrawals().Prove(txHash, index)
+}
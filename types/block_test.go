@@ -0,0 +1,76 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"execution/common"
+	ethtypes "execution/core/types"
+	"execution/crypto"
+	"execution/rlp"
+	"execution/types/gadget"
+)
+
+// Tests that headers and blocks survive their encoding, and that the header
+// hash commits to every field, the body and the execution results.
+func TestHeaderEncoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewChainSigner(big.NewInt(1))
+	txs := Transactions{
+		NewNormalTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, gadget.NewGasPrice(big.NewInt(1)), nil, signer, key),
+		NewWithdrawTransaction(1, gadget.NewGasPrice(big.NewInt(1)), []gadget.OutputCoin{{Amount: big.NewInt(2), Owner: common.Address{0x02}}}, signer, key),
+	}
+	receipts := ethtypes.Receipts{{Type: ethtypes.BHTxType, Status: ethtypes.ReceiptStatusSuccessful, CumulativeGasUsed: 21000}}
+	body := NewBody(txs)
+
+	header := NewHeaderWithBaseFee(common.Hash{0x01}, big.NewInt(2), 1000000, 0, big.NewInt(7)).
+		WithProposal(common.Address{0xaa}, 1700000000, []byte("extra")).
+		WithBody(body).
+		WithExecution(common.Hash{0x02}, ReceiptsRoot(receipts), 21000)
+
+	if header.TxHash() != txs.Root() || header.WithdrawalsRoot() != TxWithdrawals(txs).Root() {
+		t.Fatalf("body roots mismatch: have %x/%x, want %x/%x", header.TxHash(), header.WithdrawalsRoot(), txs.Root(), TxWithdrawals(txs).Root())
+	}
+	enc, err := rlp.EncodeToBytes(NewBlock(header, body))
+	if err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	var dec Block
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("failed to decode block: %v", err)
+	}
+	if dec.Hash() != header.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", dec.Hash(), header.Hash())
+	}
+	if dec.Transactions().Root() != header.TxHash() {
+		t.Errorf("decoded body root mismatch: have %x, want %x", dec.Transactions().Root(), header.TxHash())
+	}
+	// Headers without a base fee encode without it
+	legacy := NewHeader(common.Hash{0x01}, big.NewInt(2), 1000000)
+	enc, _ = rlp.EncodeToBytes(legacy)
+	var decLegacy Header
+	if err := rlp.DecodeBytes(enc, &decLegacy); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if decLegacy.BaseFee() != nil || decLegacy.Hash() != legacy.Hash() {
+		t.Errorf("legacy header mismatch: have base fee %v hash %x, want nil hash %x", decLegacy.BaseFee(), decLegacy.Hash(), legacy.Hash())
+	}
+	// Changing any part of the block changes the hash
+	variants := map[string]*Header{
+		"parent":    NewHeaderWithBaseFee(common.Hash{0x03}, big.NewInt(2), 1000000, 0, big.NewInt(7)).WithProposal(common.Address{0xaa}, 1700000000, []byte("extra")).WithBody(body).WithExecution(common.Hash{0x02}, ReceiptsRoot(receipts), 21000),
+		"coinbase":  header.WithProposal(common.Address{0xbb}, 1700000000, []byte("extra")),
+		"time":      header.WithProposal(common.Address{0xaa}, 1700000001, []byte("extra")),
+		"extra":     header.WithProposal(common.Address{0xaa}, 1700000000, nil),
+		"body":      header.WithBody(NewBody(txs[:1])),
+		"state":     header.WithExecution(common.Hash{0x04}, ReceiptsRoot(receipts), 21000),
+		"receipts":  header.WithExecution(common.Hash{0x02}, ReceiptsRoot(nil), 21000),
+		"gas used":  header.WithExecution(common.Hash{0x02}, ReceiptsRoot(receipts), 21001),
+		"base fee":  NewHeader(common.Hash{0x01}, big.NewInt(2), 1000000).WithProposal(common.Address{0xaa}, 1700000000, []byte("extra")).WithBody(body).WithExecution(common.Hash{0x02}, ReceiptsRoot(receipts), 21000),
+		"withdraws": header.WithWithdrawalsRoot(common.Hash{}),
+	}
+	for name, variant := range variants {
+		if variant.Hash() == header.Hash() {
+			t.Errorf("%s: hash not affected", name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"execution/common"
+	ethtypes "execution/core/types"
+	"execution/params"
+)
+
+// BlockValidator checks blocks against their parent and their own header, so a
+// node can reject bad blocks before committing their state. ValidateBody is run
+// before executing a block, ValidateState after.
+type BlockValidator struct {
+	chain BlockChain // Chain to look up parent blocks in
+}
+
+func NewBlockValidator(chain BlockChain) *BlockValidator {
+	return &BlockValidator{chain: chain}
+}
+
+// ValidateHeader checks that the header extends the parent: it links to the
+// parent's hash, increments the number by one and keeps the gas limit within
+// the bounds allowed relative to the parent's. The gas used must fit the limit
+// and the extra data MaximumExtraDataSize.
+func (v *BlockValidator) ValidateHeader(header, parent *Header) error {
+	if header.ParentHash() != parent.Hash() {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidParentHash, header.ParentHash(), parent.Hash())
+	}
+	if want := new(big.Int).Add(parent.Number(), common.Big1); header.Number() == nil || header.Number().Cmp(want) != 0 {
+		return fmt.Errorf("%w: have %v, want %v", ErrInvalidNumber, header.Number(), want)
+	}
+	if err := verifyGasLimit(parent.GasLimit(), header.GasLimit()); err != nil {
+		return err
+	}
+	if header.GasUsed() > header.GasLimit() {
+		return fmt.Errorf("%w: have %d, limit %d", ErrInvalidGasUsed, header.GasUsed(), header.GasLimit())
+	}
+	if uint64(len(header.extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("%w: have %d, max %d", ErrExtraDataTooLong, len(header.extra), params.MaximumExtraDataSize)
+	}
+	return nil
+}
+
+// verifyGasLimit checks that the gas limit moves by less than 1/1024 of the
+// parent's and stays within the absolute bounds.
+func verifyGasLimit(parentGasLimit, headerGasLimit uint64) error {
+	diff := int64(parentGasLimit) - int64(headerGasLimit)
+	if diff < 0 {
+		diff *= -1
+	}
+	limit := parentGasLimit / params.GasLimitBoundDivisor
+	if uint64(diff) >= limit {
+		return fmt.Errorf("%w: have %d, want %d += %d", ErrInvalidGasLimit, headerGasLimit, parentGasLimit, limit-1)
+	}
+	if headerGasLimit < params.MinGasLimit || headerGasLimit > params.MaxGasLimit {
+		return fmt.Errorf("%w: have %d, want within [%d, %d]", ErrInvalidGasLimit, headerGasLimit, params.MinGasLimit, params.MaxGasLimit)
+	}
+	return nil
+}
+
+// ValidateBody checks the header of the block against its parent, which must be
+// known to the chain, that the header's tx and withdrawal roots commit to the
+// body and that no transaction of the body is past its deadline.
+func (v *BlockValidator) ValidateBody(block *Block) error {
+	header := block.Header()
+	if header.Number() == nil || header.Number().Sign() == 0 {
+		return fmt.Errorf("%w: genesis can't be validated against a parent", ErrInvalidNumber)
+	}
+	parent := v.chain.GetBlock(header.ParentHash(), header.Number().Uint64()-1)
+	if parent == nil {
+		return fmt.Errorf("%w: %x", ErrUnknownParent, header.ParentHash())
+	}
+	if err := v.ValidateHeader(header, parent.Header()); err != nil {
+		return err
+	}
+	if hash := block.Transactions().Root(); hash != header.TxHash() {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidTxRoot, hash, header.TxHash())
+	}
+	if hash := block.Withdrawals().Root(); hash != header.WithdrawalsRoot() {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidWithdrawalsRoot, hash, header.WithdrawalsRoot())
+	}
+	for i, tx := range block.Transactions() {
+		if err := tx.CheckDeadline(header.Number().Uint64(), header.Time()); err != nil {
+			return fmt.Errorf("%w: tx %d, %x", err, i, tx.TxHash)
+		}
+	}
+	return nil
+}
+
+// ValidateState checks the results of executing the block against its header:
+// the gas used, the state root and the root of the receipts.
+func (v *BlockValidator) ValidateState(block *Block, root common.Hash, receipts ethtypes.Receipts, usedGas uint64) error {
+	header := block.Header()
+	if usedGas != header.GasUsed() {
+		return fmt.Errorf("%w: have %d, want %d", ErrInvalidGasUsed, usedGas, header.GasUsed())
+	}
+	if hash := ReceiptsRoot(receipts); hash != header.ReceiptHash() {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidReceiptRoot, hash, header.ReceiptHash())
+	}
+	if root != header.Root() {
+		return fmt.Errorf("%w: have %x, want %x", ErrInvalidStateRoot, root, header.Root())
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/core/state"
+	ethtypes "execution/core/types"
+	"execution/crypto"
+	"execution/params"
+	"execution/types/gadget"
+)
+
+// testChain is a BlockChain knowing a fixed set of blocks.
+type testChain struct {
+	blocks map[common.Hash]*Block
+}
+
+func (bc *testChain) Config() *params.ChainConfig { return nil }
+func (bc *testChain) CurrentBlock() *Header       { return nil }
+
+func (bc *testChain) GetHeader(hash common.Hash, number uint64) *Header {
+	if block := bc.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (bc *testChain) GetBlock(hash common.Hash, number uint64) *Block {
+	if block := bc.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block
+	}
+	return nil
+}
+
+func (bc *testChain) StateAt(common.Hash) (state.StateDB, error) {
+	return state.StateDB{}, nil
+}
+
+// Tests that blocks are only accepted on top of a known parent with a header and
+// body consistent with it, and execution results matching their header.
+func TestBlockValidator(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewChainSigner(big.NewInt(1))
+	txs := Transactions{
+		NewNormalTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, gadget.NewGasPrice(big.NewInt(1)), nil, signer, key),
+		NewWithdrawTransaction(1, gadget.NewGasPrice(big.NewInt(1)), []gadget.OutputCoin{{Amount: big.NewInt(2), Owner: common.Address{0x02}}}, signer, key),
+	}
+	receipts := ethtypes.Receipts{
+		{Type: ethtypes.BHTxType, Status: ethtypes.ReceiptStatusSuccessful, CumulativeGasUsed: 21000},
+		{Type: ethtypes.BHTxType, Status: ethtypes.ReceiptStatusSuccessful, CumulativeGasUsed: 42000},
+	}
+	parent := NewBlock(NewHeader(common.Hash{}, big.NewInt(0), 1000000), NewBody(nil))
+	chain := &testChain{blocks: map[common.Hash]*Block{parent.Hash(): parent}}
+	validator := NewBlockValidator(chain)
+
+	// block builds a child of the parent, letting the header be modified before
+	// it commits to the body
+	body := NewBody(txs)
+	block := func(modify func(*Header) *Header) *Block {
+		header := NewHeader(parent.Hash(), big.NewInt(1), 1000000).WithBody(body).WithExecution(common.Hash{0x01}, ReceiptsRoot(receipts), 42000)
+		if modify != nil {
+			header = modify(header)
+		}
+		return NewBlock(header, body)
+	}
+	if err := validator.ValidateBody(block(nil)); err != nil {
+		t.Fatalf("failed to validate body: %v", err)
+	}
+	if err := validator.ValidateState(block(nil), common.Hash{0x01}, receipts, 42000); err != nil {
+		t.Fatalf("failed to validate state: %v", err)
+	}
+	bodyTests := []struct {
+		name   string
+		modify func(*Header) *Header
+		err    error
+	}{
+		{
+			name: "unknown parent",
+			modify: func(h *Header) *Header {
+				return NewHeader(common.Hash{0xff}, big.NewInt(1), 1000000).WithBody(body)
+			},
+			err: ErrUnknownParent,
+		},
+		{
+			name: "skipped number",
+			modify: func(h *Header) *Header {
+				return NewHeader(parent.Hash(), big.NewInt(2), 1000000).WithBody(body)
+			},
+			err: ErrUnknownParent,
+		},
+		{
+			name: "gas limit raised too far",
+			modify: func(h *Header) *Header {
+				return NewHeader(parent.Hash(), big.NewInt(1), 1000000+1000000/params.GasLimitBoundDivisor).WithBody(body)
+			},
+			err: ErrInvalidGasLimit,
+		},
+		{
+			name: "gas limit lowered too far",
+			modify: func(h *Header) *Header {
+				return NewHeader(parent.Hash(), big.NewInt(1), 1000000-1000000/params.GasLimitBoundDivisor).WithBody(body)
+			},
+			err: ErrInvalidGasLimit,
+		},
+		{
+			name: "gas used over limit",
+			modify: func(h *Header) *Header {
+				return h.WithExecution(h.Root(), h.ReceiptHash(), 1000001)
+			},
+			err: ErrInvalidGasUsed,
+		},
+		{
+			name: "extra too long",
+			modify: func(h *Header) *Header {
+				return h.WithProposal(common.Address{}, 0, make([]byte, params.MaximumExtraDataSize+1))
+			},
+			err: ErrExtraDataTooLong,
+		},
+		{
+			name: "tx root",
+			modify: func(h *Header) *Header {
+				return h.WithBody(NewBody(txs[:1])).WithWithdrawalsRoot(h.WithdrawalsRoot())
+			},
+			err: ErrInvalidTxRoot,
+		},
+		{
+			name: "withdrawal root",
+			modify: func(h *Header) *Header {
+				return h.WithWithdrawalsRoot(common.Hash{})
+			},
+			err: ErrInvalidWithdrawalsRoot,
+		},
+	}
+	for _, tt := range bodyTests {
+		if err := validator.ValidateBody(block(tt.modify)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	// A header not linking to the parent is refused even if its parent is known
+	if err := validator.ValidateHeader(block(nil).Header(), block(nil).Header()); !errors.Is(err, ErrInvalidParentHash) {
+		t.Errorf("parent hash error mismatch: have %v, want %v", err, ErrInvalidParentHash)
+	}
+	if err := validator.ValidateHeader(NewHeader(parent.Hash(), big.NewInt(2), 1000000), parent.Header()); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("number error mismatch: have %v, want %v", err, ErrInvalidNumber)
+	}
+	stateTests := []struct {
+		name     string
+		root     common.Hash
+		receipts ethtypes.Receipts
+		usedGas  uint64
+		err      error
+	}{
+		{name: "gas used", root: common.Hash{0x01}, receipts: receipts, usedGas: 21000, err: ErrInvalidGasUsed},
+		{name: "receipts", root: common.Hash{0x01}, receipts: receipts[:1], usedGas: 42000, err: ErrInvalidReceiptRoot},
+		{name: "state root", root: common.Hash{0x02}, receipts: receipts, usedGas: 42000, err: ErrInvalidStateRoot},
+	}
+	for _, tt := range stateTests {
+		if err := validator.ValidateState(block(nil), tt.root, tt.receipts, tt.usedGas); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+// Tests that blocks including transactions past their deadline are refused.
+func TestBlockValidatorDeadline(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewChainSigner(big.NewInt(1))
+
+	parent := NewBlock(NewHeader(common.Hash{}, big.NewInt(0), 1000000), NewBody(nil))
+	validator := NewBlockValidator(&testChain{blocks: map[common.Hash]*Block{parent.Hash(): parent}})
+
+	tests := []struct {
+		deadline gadget.Deadline
+		err      error
+	}{
+		{deadline: gadget.Deadline{Number: 1}},
+		{deadline: gadget.Deadline{Time: 200}},
+		{deadline: gadget.Deadline{Time: 100}, err: ErrTxExpired},
+	}
+	for i, tt := range tests {
+		tx := NewNormalTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, gadget.NewGasPrice(big.NewInt(1)), nil, signer, key)
+		tx.ValidUntil = &tt.deadline
+		tx.Sign(signer, key)
+
+		body := NewBody(Transactions{tx})
+		header := NewHeader(parent.Hash(), big.NewInt(1), 1000000).WithProposal(common.Address{}, 200, nil).WithBody(body)
+		if err := validator.ValidateBody(NewBlock(header, body)); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
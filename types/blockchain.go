@@ -15,6 +15,10 @@ type BlockChain interface {
 	// CurrentBlock returns the current head of the chain.
 	CurrentBlock() *Header
 
+	// GetHeader retrieves a specific header, used to walk chains during pool
+	// resets without loading their bodies.
+	GetHeader(hash common.Hash, number uint64) *Header
+
 	// GetBlock retrieves a specific block, used during pool resets.
 	GetBlock(hash common.Hash, number uint64) *Block
 
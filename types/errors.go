@@ -3,6 +3,28 @@ package types
 import "errors"
 
 var (
-	ErrGasUintOverflow = errors.New("gas uint overflow")
-	ErrCannotMarshal   = errors.New("cannot marshal")
+	ErrGasUintOverflow    = errors.New("gas uint overflow")
+	ErrCannotMarshal      = errors.New("cannot marshal")
+	ErrInvalidSig         = errors.New("invalid transaction signature")
+	ErrInvalidChainId     = errors.New("invalid chain id for signer")
+	ErrInvalidTxHash      = errors.New("transaction hash does not match its content")
+	ErrTxExpired          = errors.New("transaction past its deadline")
+	ErrInvalidTxEncoding  = errors.New("invalid transaction encoding")
+	ErrInvalidWitness     = errors.New("invalid input coin witness")
+	ErrCoinLocked         = errors.New("input coin locked")
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+)
+
+// Block validation errors, see BlockValidator.
+var (
+	ErrUnknownParent          = errors.New("unknown parent block")
+	ErrInvalidParentHash      = errors.New("parent hash mismatch")
+	ErrInvalidNumber          = errors.New("invalid block number")
+	ErrInvalidGasLimit        = errors.New("invalid gas limit")
+	ErrInvalidGasUsed         = errors.New("invalid gas used")
+	ErrExtraDataTooLong       = errors.New("extra data too long")
+	ErrInvalidTxRoot          = errors.New("transaction root mismatch")
+	ErrInvalidWithdrawalsRoot = errors.New("withdrawal root mismatch")
+	ErrInvalidStateRoot       = errors.New("state root mismatch")
+	ErrInvalidReceiptRoot     = errors.New("receipt root mismatch")
 )
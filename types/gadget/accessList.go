@@ -1,11 +1,32 @@
 package gadget
 
-type AccessList struct{}
+import "execution/common"
+
+// AccessTuple is the element type of an access list. A tuple without storage
+// keys refers to the account itself (balance, nonce, code).
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys,omitempty"`
+}
+
+// AccessList declares the state a transaction reads and writes, which is what
+// the executor needs to schedule non-conflicting transactions in parallel.
+type AccessList struct {
+	Reads  []AccessTuple `json:"reads,omitempty"`
+	Writes []AccessTuple `json:"writes,omitempty"`
+}
 
 func (al AccessList) Len() int {
-	return 0
+	return len(al.Reads) + len(al.Writes)
 }
 
 func (al AccessList) StorageKeys() int {
-	return 0
+	sum := 0
+	for _, tuple := range al.Reads {
+		sum += len(tuple.StorageKeys)
+	}
+	for _, tuple := range al.Writes {
+		sum += len(tuple.StorageKeys)
+	}
+	return sum
 }
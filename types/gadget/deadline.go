@@ -0,0 +1,17 @@
+package gadget
+
+// Deadline is the last block a transaction may be included in, given by block
+// number, by block timestamp or both. Zero fields are not enforced.
+type Deadline struct {
+	Number uint64 `json:"number,omitempty"` // Highest block number the transaction may be included in
+	Time   uint64 `json:"time,omitempty"`   // Latest block timestamp (unix seconds) the transaction may be included at
+}
+
+// Passed reports whether a block with the given number and timestamp is past
+// the deadline. A zero time skips the timestamp check.
+func (d *Deadline) Passed(number, time uint64) bool {
+	if d.Number != 0 && number > d.Number {
+		return true
+	}
+	return d.Time != 0 && time != 0 && time > d.Time
+}
@@ -1,11 +1,106 @@
 package gadget
 
-import "math/big"
+import (
+	"errors"
+	"math/big"
+)
 
+var ErrGasFeeCapTooLow = errors.New("fee cap less than base fee")
+
+// GasPrice carries the pricing of a transaction. Legacy transactions only set
+// Price, which then acts as both the fee cap and the tip cap. Dynamic fee
+// transactions set FeeCap and TipCap instead.
 type GasPrice struct {
-	Price *big.Int `json:"price,omitempty"`
+	Price  *big.Int `json:"price,omitempty"`
+	FeeCap *big.Int `json:"feeCap,omitempty"` // Maximum total price per gas, base fee included
+	TipCap *big.Int `json:"tipCap,omitempty"` // Maximum price per gas paid on top of the base fee
 }
 
 func NewGasPrice(price *big.Int) *GasPrice {
 	return &GasPrice{Price: price}
 }
+
+func NewDynamicGasPrice(feeCap *big.Int, tipCap *big.Int) *GasPrice {
+	return &GasPrice{FeeCap: feeCap, TipCap: tipCap}
+}
+
+// GasFeeCap returns the maximum price per gas the sender is willing to pay.
+func (gp *GasPrice) GasFeeCap() *big.Int {
+	if gp.FeeCap != nil {
+		return gp.FeeCap
+	}
+	return gp.Price
+}
+
+// GasTipCap returns the maximum price per gas the sender is willing to pay on
+// top of the base fee.
+func (gp *GasPrice) GasTipCap() *big.Int {
+	if gp.TipCap != nil {
+		return gp.TipCap
+	}
+	return gp.Price
+}
+
+// GasFeeCapCmp compares the fee cap of two gas prices.
+func (gp *GasPrice) GasFeeCapCmp(other *GasPrice) int {
+	return gp.GasFeeCap().Cmp(other.GasFeeCap())
+}
+
+// GasFeeCapIntCmp compares the fee cap of the gas price against the given fee cap.
+func (gp *GasPrice) GasFeeCapIntCmp(other *big.Int) int {
+	return gp.GasFeeCap().Cmp(other)
+}
+
+// GasTipCapCmp compares the tip cap of two gas prices.
+func (gp *GasPrice) GasTipCapCmp(other *GasPrice) int {
+	return gp.GasTipCap().Cmp(other.GasTipCap())
+}
+
+// GasTipCapIntCmp compares the tip cap of the gas price against the given tip cap.
+func (gp *GasPrice) GasTipCapIntCmp(other *big.Int) int {
+	return gp.GasTipCap().Cmp(other)
+}
+
+// EffectiveGasTip returns the effective miner tip for the given base fee. A nil
+// base fee returns the tip cap. An error is returned if the fee cap is below
+// the base fee, in which case the returned tip is negative.
+func (gp *GasPrice) EffectiveGasTip(baseFee *big.Int) (*big.Int, error) {
+	if baseFee == nil {
+		return new(big.Int).Set(gp.GasTipCap()), nil
+	}
+	var err error
+	gasFeeCap := gp.GasFeeCap()
+	if gasFeeCap.Cmp(baseFee) < 0 {
+		err = ErrGasFeeCapTooLow
+	}
+	tip := new(big.Int).Sub(gasFeeCap, baseFee)
+	if tip.Cmp(gp.GasTipCap()) > 0 {
+		tip.Set(gp.GasTipCap())
+	}
+	return tip, err
+}
+
+// EffectiveGasTipValue is identical to EffectiveGasTip, but does not return an
+// error in case the effective tip is negative.
+func (gp *GasPrice) EffectiveGasTipValue(baseFee *big.Int) *big.Int {
+	tip, _ := gp.EffectiveGasTip(baseFee)
+	return tip
+}
+
+// EffectiveGasTipCmp compares the effective tips of two gas prices assuming the
+// given base fee.
+func (gp *GasPrice) EffectiveGasTipCmp(other *GasPrice, baseFee *big.Int) int {
+	if baseFee == nil {
+		return gp.GasTipCapCmp(other)
+	}
+	return gp.EffectiveGasTipValue(baseFee).Cmp(other.EffectiveGasTipValue(baseFee))
+}
+
+// EffectiveGasTipIntCmp compares the effective tip of the gas price against
+// the given tip assuming the given base fee.
+func (gp *GasPrice) EffectiveGasTipIntCmp(other *big.Int, baseFee *big.Int) int {
+	if baseFee == nil {
+		return gp.GasTipCapIntCmp(other)
+	}
+	return gp.EffectiveGasTipValue(baseFee).Cmp(other)
+}
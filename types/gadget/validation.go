@@ -13,6 +13,7 @@ import (
 var (
 	ErrInvalidSignature = errors.New("invalid signature")
 	ErrInvalidPubKey    = errors.New("invalid public key")
+	ErrInvalidChainID   = errors.New("signature for a different chain")
 )
 
 type Validation struct {
@@ -29,11 +30,42 @@ func validateSignatureValues(r, s *big.Int, v byte) bool {
 	return r.Cmp(crypto.Secp256k1N) < 0 && s.Cmp(crypto.Secp256k1N) < 0 && (v == 0 || v == 1)
 }
 
-func (sign *Validation) GetFrom(input common.Hash) (common.Address, error) {
-	if sign.V.BitLen() > 8 {
-		return common.Address{}, ErrInvalidSignature
+// ChainID returns the chain ID encoded in the V value of the signature, nil for
+// signatures without one.
+func (sign *Validation) ChainID() *big.Int {
+	if sign.V.Cmp(big.NewInt(35)) < 0 {
+		return nil
+	}
+	return new(big.Int).Rsh(new(big.Int).Sub(sign.V, big.NewInt(35)), 1)
+}
+
+// recoveryID returns the recovery id of the signature, checking that its V value
+// encodes the given chain ID as V = chainID*2 + 35 + id. Signatures without a
+// chain ID encode V = 27 + id.
+func (sign *Validation) recoveryID(chainID *big.Int) (byte, error) {
+	var base *big.Int
+	switch have := sign.ChainID(); {
+	case have == nil && chainID == nil:
+		base = big.NewInt(27)
+	case have != nil && chainID != nil && have.Cmp(chainID) == 0:
+		base = new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35))
+	default:
+		return 0, ErrInvalidChainID
+	}
+	v := new(big.Int).Sub(sign.V, base)
+	if v.Sign() < 0 || v.Cmp(big.NewInt(1)) > 0 {
+		return 0, ErrInvalidSignature
+	}
+	return byte(v.Uint64()), nil
+}
+
+// GetFrom recovers the address which signed the input for the given chain, nil
+// for signatures without a chain ID. Signatures for other chains are refused.
+func (sign *Validation) GetFrom(input common.Hash, chainID *big.Int) (common.Address, error) {
+	v, err := sign.recoveryID(chainID)
+	if err != nil {
+		return common.Address{}, err
 	}
-	v := byte(sign.V.Uint64() - 27)
 
 	if !validateSignatureValues(sign.R, sign.S, v) {
 		return common.Address{}, ErrInvalidSignature
@@ -60,14 +92,19 @@ func (sign *Validation) GetFrom(input common.Hash) (common.Address, error) {
 	return addr, nil
 }
 
-func (sign *Validation) Sign(input common.Hash, prv *ecdsa.PrivateKey) {
+// Sign signs the input for the given chain, nil to sign without a chain ID.
+func (sign *Validation) Sign(input common.Hash, chainID *big.Int, prv *ecdsa.PrivateKey) {
 	sig, err := crypto.Sign(input[:], prv)
 	if err != nil {
 		panic(err)
 	}
 	sign.R = new(big.Int).SetBytes(sig[:32])
 	sign.S = new(big.Int).SetBytes(sig[32:64])
-	sign.V = new(big.Int).SetBytes([]byte{sig[64] + 27})
+	if chainID == nil {
+		sign.V = new(big.Int).SetBytes([]byte{sig[64] + 27})
+	} else {
+		sign.V = new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35+int64(sig[64])))
+	}
 }
 
 func FromECDSAPub(pub *ecdsa.PublicKey) []byte {
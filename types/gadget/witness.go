@@ -1,3 +1,126 @@
 package gadget
 
-type Witness struct{}
+import (
+	"crypto/ecdsa"
+	"errors"
+	"execution/common"
+	"fmt"
+
+	"execution/crypto"
+	"execution/rlp"
+)
+
+var (
+	ErrInvalidCondition    = errors.New("invalid spending condition")
+	ErrPreimageMismatch    = errors.New("preimage does not match hash lock")
+	ErrUnexpectedSigner    = errors.New("unexpected witness signer")
+	ErrNotEnoughSignatures = errors.New("not enough witness signatures")
+)
+
+// Condition is the spending condition of a coin. A coin is spent by a witness
+// signed by Threshold distinct keys out of Keys, revealing the preimage of
+// HashLock if set, in a block past LockUntil if set.
+type Condition struct {
+	Keys      []common.Address // Keys allowed to sign for the coin
+	Threshold uint64           // Number of distinct keys which must sign
+	HashLock  common.Hash      // Keccak256 hash of the preimage to reveal, unused if zero
+	LockUntil Deadline         // Last block the coin is locked in, unused if zero
+}
+
+// NewKeyCondition returns the condition of a coin spent by a single key.
+func NewKeyCondition(owner common.Address) *Condition {
+	return &Condition{Keys: []common.Address{owner}, Threshold: 1}
+}
+
+// ParseCondition decodes the owner of an input coin into its spending condition.
+// Owners of a single address are spent by the matching key, others are the RLP
+// encoding of a condition.
+func ParseCondition(owner []byte) (*Condition, error) {
+	if len(owner) == common.AddressLength {
+		return NewKeyCondition(common.BytesToAddress(owner)), nil
+	}
+	var cond Condition
+	if err := rlp.DecodeBytes(owner, &cond); err != nil {
+		return nil, err
+	}
+	if err := cond.validate(); err != nil {
+		return nil, err
+	}
+	return &cond, nil
+}
+
+// Owner returns the encoding of the condition to use as owner of a coin.
+func (c *Condition) Owner() ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if len(c.Keys) == 1 && c.Threshold == 1 && c.HashLock == (common.Hash{}) && c.LockUntil == (Deadline{}) {
+		return c.Keys[0].Bytes(), nil
+	}
+	return rlp.EncodeToBytes(c)
+}
+
+// Locked reports whether the coin can't be spent yet in a block with the given
+// number and timestamp. A zero time only unlocks coins locked by number.
+func (c *Condition) Locked(number, time uint64) bool {
+	return c.LockUntil != (Deadline{}) && !c.LockUntil.Passed(number, time)
+}
+
+// validate checks that the condition can be satisfied and is not trivially so.
+func (c *Condition) validate() error {
+	if c.Threshold > uint64(len(c.Keys)) {
+		return ErrInvalidCondition
+	}
+	if c.Threshold == 0 && c.HashLock == (common.Hash{}) {
+		return ErrInvalidCondition
+	}
+	return nil
+}
+
+// Witness satisfies the spending condition of the input coins referencing it.
+type Witness struct {
+	Signatures []Validation `json:"signatures,omitempty"` // Signatures of the witness hash of the transaction
+	Preimage   []byte       `json:"preimage,omitempty"`   // Preimage of the hash lock of the condition
+}
+
+// Sign adds a signature of the witness hash of a transaction to the witness.
+// Witness signatures carry no chain ID, the witness hash commits to coins
+// created by chain specific transactions already.
+func (w *Witness) Sign(input common.Hash, prv *ecdsa.PrivateKey) {
+	var sig Validation
+	sig.Sign(input, nil, prv)
+	w.Signatures = append(w.Signatures, sig)
+}
+
+// Satisfies checks that the witness satisfies a condition, given the witness
+// hash its signatures are made over. Locks are not checked, see Locked.
+func (w *Witness) Satisfies(cond *Condition, input common.Hash) error {
+	if cond.HashLock != (common.Hash{}) && crypto.Keccak256Hash(w.Preimage) != cond.HashLock {
+		return ErrPreimageMismatch
+	}
+	allowed := make(map[common.Address]bool, len(cond.Keys))
+	for _, key := range cond.Keys {
+		allowed[key] = true
+	}
+	var signed uint64
+	for i := range w.Signatures {
+		sig := &w.Signatures[i]
+		if sig.R == nil || sig.S == nil || sig.V == nil {
+			return ErrInvalidSignature
+		}
+		signer, err := sig.GetFrom(input, nil)
+		if err != nil {
+			return err
+		}
+		// Each key may sign once, signatures of other keys are refused
+		if !allowed[signer] {
+			return fmt.Errorf("%w: %v", ErrUnexpectedSigner, signer)
+		}
+		delete(allowed, signer)
+		signed++
+	}
+	if signed < cond.Threshold {
+		return fmt.Errorf("%w: have %d, want %d", ErrNotEnoughSignatures, signed, cond.Threshold)
+	}
+	return nil
+}
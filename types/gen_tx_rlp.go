@@ -0,0 +1,745 @@
+// Code generated by rlpgen. DO NOT EDIT.
+
+//go:build !norlpgen
+// +build !norlpgen
+
+package types
+
+import "execution/common"
+import "execution/rlp"
+import "execution/types/gadget"
+import "io"
+import "math/big"
+
+func (obj *rlpTx) EncodeRLP(_w io.Writer) error {
+	w := rlp.NewEncoderBuffer(_w)
+	_tmp0 := w.List()
+	w.WriteBytes(obj.TxHash[:])
+	w.WriteBytes(obj.From[:])
+	w.WriteUint64(obj.Nonce)
+	w.WriteUint64(obj.GasLimit)
+	_tmp1 := w.List()
+	for _, _tmp2 := range obj.GasPrice {
+		if _tmp2 == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if _tmp2.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(_tmp2)
+		}
+	}
+	w.ListEnd(_tmp1)
+	if obj.Value == nil {
+		w.Write(rlp.EmptyString)
+	} else {
+		if obj.Value.Sign() == -1 {
+			return rlp.ErrNegativeBigInt
+		}
+		w.WriteBigInt(obj.Value)
+	}
+	if obj.Validation == nil {
+		w.Write([]byte{0xC0})
+	} else {
+		_tmp3 := w.List()
+		if obj.Validation.R == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if obj.Validation.R.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(obj.Validation.R)
+		}
+		if obj.Validation.S == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if obj.Validation.S.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(obj.Validation.S)
+		}
+		if obj.Validation.V == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if obj.Validation.V.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(obj.Validation.V)
+		}
+		w.ListEnd(_tmp3)
+	}
+	_tmp4 := w.List()
+	for _, _tmp5 := range obj.InputCoins {
+		_tmp6 := w.List()
+		w.WriteBytes(_tmp5.TxHash[:])
+		w.WriteUint64(uint64(_tmp5.Index))
+		if _tmp5.Amount == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if _tmp5.Amount.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(_tmp5.Amount)
+		}
+		w.WriteUint64(uint64(_tmp5.WitnessIndex))
+		w.WriteBytes(_tmp5.Owner)
+		w.ListEnd(_tmp6)
+	}
+	w.ListEnd(_tmp4)
+	_tmp7 := w.List()
+	for _, _tmp8 := range obj.Witnesses {
+		_tmp9 := w.List()
+		_tmp10 := w.List()
+		for _, _tmp11 := range _tmp8.Signatures {
+			_tmp12 := w.List()
+			if _tmp11.R == nil {
+				w.Write(rlp.EmptyString)
+			} else {
+				if _tmp11.R.Sign() == -1 {
+					return rlp.ErrNegativeBigInt
+				}
+				w.WriteBigInt(_tmp11.R)
+			}
+			if _tmp11.S == nil {
+				w.Write(rlp.EmptyString)
+			} else {
+				if _tmp11.S.Sign() == -1 {
+					return rlp.ErrNegativeBigInt
+				}
+				w.WriteBigInt(_tmp11.S)
+			}
+			if _tmp11.V == nil {
+				w.Write(rlp.EmptyString)
+			} else {
+				if _tmp11.V.Sign() == -1 {
+					return rlp.ErrNegativeBigInt
+				}
+				w.WriteBigInt(_tmp11.V)
+			}
+			w.ListEnd(_tmp12)
+		}
+		w.ListEnd(_tmp10)
+		w.WriteBytes(_tmp8.Preimage)
+		w.ListEnd(_tmp9)
+	}
+	w.ListEnd(_tmp7)
+	_tmp13 := w.List()
+	for _, _tmp14 := range obj.OutputCoins {
+		_tmp15 := w.List()
+		if _tmp14.Amount == nil {
+			w.Write(rlp.EmptyString)
+		} else {
+			if _tmp14.Amount.Sign() == -1 {
+				return rlp.ErrNegativeBigInt
+			}
+			w.WriteBigInt(_tmp14.Amount)
+		}
+		w.WriteBytes(_tmp14.Owner[:])
+		w.ListEnd(_tmp15)
+	}
+	w.ListEnd(_tmp13)
+	w.WriteBytes(obj.To[:])
+	w.WriteBytes(obj.Data)
+	if obj.AccessList == nil {
+		w.Write([]byte{0xC0})
+	} else {
+		_tmp16 := w.List()
+		_tmp17 := w.List()
+		for _, _tmp18 := range obj.AccessList.Reads {
+			_tmp19 := w.List()
+			w.WriteBytes(_tmp18.Address[:])
+			_tmp20 := w.List()
+			for _, _tmp21 := range _tmp18.StorageKeys {
+				w.WriteBytes(_tmp21[:])
+			}
+			w.ListEnd(_tmp20)
+			w.ListEnd(_tmp19)
+		}
+		w.ListEnd(_tmp17)
+		_tmp22 := w.List()
+		for _, _tmp23 := range obj.AccessList.Writes {
+			_tmp24 := w.List()
+			w.WriteBytes(_tmp23.Address[:])
+			_tmp25 := w.List()
+			for _, _tmp26 := range _tmp23.StorageKeys {
+				w.WriteBytes(_tmp26[:])
+			}
+			w.ListEnd(_tmp25)
+			w.ListEnd(_tmp24)
+		}
+		w.ListEnd(_tmp22)
+		w.ListEnd(_tmp16)
+	}
+	if obj.Refund == nil {
+		w.Write([]byte{0xC0})
+	} else {
+		_tmp27 := w.List()
+		w.ListEnd(_tmp27)
+	}
+	w.WriteBytes(obj.Extend)
+	if obj.StrictAccessList == nil {
+		w.Write([]byte{0xC0})
+	} else {
+		_tmp28 := w.List()
+		_tmp29 := w.List()
+		for _, _tmp30 := range obj.StrictAccessList.Reads {
+			_tmp31 := w.List()
+			w.WriteBytes(_tmp30.Address[:])
+			_tmp32 := w.List()
+			for _, _tmp33 := range _tmp30.StorageKeys {
+				w.WriteBytes(_tmp33[:])
+			}
+			w.ListEnd(_tmp32)
+			w.ListEnd(_tmp31)
+		}
+		w.ListEnd(_tmp29)
+		_tmp34 := w.List()
+		for _, _tmp35 := range obj.StrictAccessList.Writes {
+			_tmp36 := w.List()
+			w.WriteBytes(_tmp35.Address[:])
+			_tmp37 := w.List()
+			for _, _tmp38 := range _tmp35.StorageKeys {
+				w.WriteBytes(_tmp38[:])
+			}
+			w.ListEnd(_tmp37)
+			w.ListEnd(_tmp36)
+		}
+		w.ListEnd(_tmp34)
+		w.ListEnd(_tmp28)
+	}
+	if obj.ValidUntil == nil {
+		w.Write([]byte{0xC0})
+	} else {
+		_tmp39 := w.List()
+		w.WriteUint64(obj.ValidUntil.Number)
+		w.WriteUint64(obj.ValidUntil.Time)
+		w.ListEnd(_tmp39)
+	}
+	w.ListEnd(_tmp0)
+	return w.Flush()
+}
+
+func (obj *rlpTx) DecodeRLP(dec *rlp.Stream) error {
+	var _tmp0 rlpTx
+	{
+		if _, err := dec.List(); err != nil {
+			return err
+		}
+		// TxHash:
+		var _tmp1 common.Hash
+		if err := dec.ReadBytes(_tmp1[:]); err != nil {
+			return err
+		}
+		_tmp0.TxHash = _tmp1
+		// From:
+		var _tmp2 common.Address
+		if err := dec.ReadBytes(_tmp2[:]); err != nil {
+			return err
+		}
+		_tmp0.From = _tmp2
+		// Nonce:
+		_tmp3, err := dec.Uint64()
+		if err != nil {
+			return err
+		}
+		_tmp0.Nonce = _tmp3
+		// GasLimit:
+		_tmp4, err := dec.Uint64()
+		if err != nil {
+			return err
+		}
+		_tmp0.GasLimit = _tmp4
+		// GasPrice:
+		var _tmp5 []*big.Int
+		if _, err := dec.List(); err != nil {
+			return err
+		}
+		for dec.MoreDataInList() {
+			_tmp6, err := dec.BigInt()
+			if err != nil {
+				return err
+			}
+			_tmp5 = append(_tmp5, _tmp6)
+		}
+		if err := dec.ListEnd(); err != nil {
+			return err
+		}
+		_tmp0.GasPrice = _tmp5
+		// Value:
+		_tmp7, err := dec.BigInt()
+		if err != nil {
+			return err
+		}
+		_tmp0.Value = _tmp7
+		// Validation:
+		var _tmp12 *gadget.Validation
+		if _tmp13, _tmp14, err := dec.Kind(); err != nil {
+			return err
+		} else if _tmp14 != 0 || _tmp13 != rlp.List {
+			var _tmp8 gadget.Validation
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// R:
+				_tmp9, err := dec.BigInt()
+				if err != nil {
+					return err
+				}
+				_tmp8.R = _tmp9
+				// S:
+				_tmp10, err := dec.BigInt()
+				if err != nil {
+					return err
+				}
+				_tmp8.S = _tmp10
+				// V:
+				_tmp11, err := dec.BigInt()
+				if err != nil {
+					return err
+				}
+				_tmp8.V = _tmp11
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp12 = &_tmp8
+		} else if _, err := dec.Raw(); err != nil {
+			return err
+		}
+		_tmp0.Validation = _tmp12
+		// InputCoins:
+		var _tmp15 []gadget.InputCoin
+		if _, err := dec.List(); err != nil {
+			return err
+		}
+		for dec.MoreDataInList() {
+			var _tmp16 gadget.InputCoin
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// TxHash:
+				var _tmp17 common.Hash
+				if err := dec.ReadBytes(_tmp17[:]); err != nil {
+					return err
+				}
+				_tmp16.TxHash = _tmp17
+				// Index:
+				_tmp18, err := dec.Uint32()
+				if err != nil {
+					return err
+				}
+				_tmp16.Index = _tmp18
+				// Amount:
+				_tmp19, err := dec.BigInt()
+				if err != nil {
+					return err
+				}
+				_tmp16.Amount = _tmp19
+				// WitnessIndex:
+				_tmp20, err := dec.Uint32()
+				if err != nil {
+					return err
+				}
+				_tmp16.WitnessIndex = _tmp20
+				// Owner:
+				_tmp21, err := dec.Bytes()
+				if err != nil {
+					return err
+				}
+				_tmp16.Owner = _tmp21
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp15 = append(_tmp15, _tmp16)
+		}
+		if err := dec.ListEnd(); err != nil {
+			return err
+		}
+		_tmp0.InputCoins = _tmp15
+		// Witnesses:
+		var _tmp22 []gadget.Witness
+		if _, err := dec.List(); err != nil {
+			return err
+		}
+		for dec.MoreDataInList() {
+			var _tmp23 gadget.Witness
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// Signatures:
+				var _tmp24 []gadget.Validation
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				for dec.MoreDataInList() {
+					var _tmp25 gadget.Validation
+					{
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						// R:
+						_tmp26, err := dec.BigInt()
+						if err != nil {
+							return err
+						}
+						_tmp25.R = _tmp26
+						// S:
+						_tmp27, err := dec.BigInt()
+						if err != nil {
+							return err
+						}
+						_tmp25.S = _tmp27
+						// V:
+						_tmp28, err := dec.BigInt()
+						if err != nil {
+							return err
+						}
+						_tmp25.V = _tmp28
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+					}
+					_tmp24 = append(_tmp24, _tmp25)
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+				_tmp23.Signatures = _tmp24
+				// Preimage:
+				_tmp29, err := dec.Bytes()
+				if err != nil {
+					return err
+				}
+				_tmp23.Preimage = _tmp29
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp22 = append(_tmp22, _tmp23)
+		}
+		if err := dec.ListEnd(); err != nil {
+			return err
+		}
+		_tmp0.Witnesses = _tmp22
+		// OutputCoins:
+		var _tmp30 []gadget.OutputCoin
+		if _, err := dec.List(); err != nil {
+			return err
+		}
+		for dec.MoreDataInList() {
+			var _tmp31 gadget.OutputCoin
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// Amount:
+				_tmp32, err := dec.BigInt()
+				if err != nil {
+					return err
+				}
+				_tmp31.Amount = _tmp32
+				// Owner:
+				var _tmp33 common.Address
+				if err := dec.ReadBytes(_tmp33[:]); err != nil {
+					return err
+				}
+				_tmp31.Owner = _tmp33
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp30 = append(_tmp30, _tmp31)
+		}
+		if err := dec.ListEnd(); err != nil {
+			return err
+		}
+		_tmp0.OutputCoins = _tmp30
+		// To:
+		var _tmp34 common.Address
+		if err := dec.ReadBytes(_tmp34[:]); err != nil {
+			return err
+		}
+		_tmp0.To = _tmp34
+		// Data:
+		_tmp35, err := dec.Bytes()
+		if err != nil {
+			return err
+		}
+		_tmp0.Data = _tmp35
+		// AccessList:
+		var _tmp47 *gadget.AccessList
+		if _tmp48, _tmp49, err := dec.Kind(); err != nil {
+			return err
+		} else if _tmp49 != 0 || _tmp48 != rlp.List {
+			var _tmp36 gadget.AccessList
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// Reads:
+				var _tmp37 []gadget.AccessTuple
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				for dec.MoreDataInList() {
+					var _tmp38 gadget.AccessTuple
+					{
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						// Address:
+						var _tmp39 common.Address
+						if err := dec.ReadBytes(_tmp39[:]); err != nil {
+							return err
+						}
+						_tmp38.Address = _tmp39
+						// StorageKeys:
+						var _tmp40 []common.Hash
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						for dec.MoreDataInList() {
+							var _tmp41 common.Hash
+							if err := dec.ReadBytes(_tmp41[:]); err != nil {
+								return err
+							}
+							_tmp40 = append(_tmp40, _tmp41)
+						}
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+						_tmp38.StorageKeys = _tmp40
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+					}
+					_tmp37 = append(_tmp37, _tmp38)
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+				_tmp36.Reads = _tmp37
+				// Writes:
+				var _tmp42 []gadget.AccessTuple
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				for dec.MoreDataInList() {
+					var _tmp43 gadget.AccessTuple
+					{
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						// Address:
+						var _tmp44 common.Address
+						if err := dec.ReadBytes(_tmp44[:]); err != nil {
+							return err
+						}
+						_tmp43.Address = _tmp44
+						// StorageKeys:
+						var _tmp45 []common.Hash
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						for dec.MoreDataInList() {
+							var _tmp46 common.Hash
+							if err := dec.ReadBytes(_tmp46[:]); err != nil {
+								return err
+							}
+							_tmp45 = append(_tmp45, _tmp46)
+						}
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+						_tmp43.StorageKeys = _tmp45
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+					}
+					_tmp42 = append(_tmp42, _tmp43)
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+				_tmp36.Writes = _tmp42
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp47 = &_tmp36
+		} else if _, err := dec.Raw(); err != nil {
+			return err
+		}
+		_tmp0.AccessList = _tmp47
+		// Refund:
+		var _tmp51 *gadget.Refund
+		if _tmp52, _tmp53, err := dec.Kind(); err != nil {
+			return err
+		} else if _tmp53 != 0 || _tmp52 != rlp.List {
+			var _tmp50 gadget.Refund
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp51 = &_tmp50
+		} else if _, err := dec.Raw(); err != nil {
+			return err
+		}
+		_tmp0.Refund = _tmp51
+		// Extend:
+		_tmp54, err := dec.Bytes()
+		if err != nil {
+			return err
+		}
+		_tmp0.Extend = _tmp54
+		// StrictAccessList:
+		var _tmp66 *gadget.AccessList
+		if _tmp67, _tmp68, err := dec.Kind(); err != nil {
+			return err
+		} else if _tmp68 != 0 || _tmp67 != rlp.List {
+			var _tmp55 gadget.AccessList
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// Reads:
+				var _tmp56 []gadget.AccessTuple
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				for dec.MoreDataInList() {
+					var _tmp57 gadget.AccessTuple
+					{
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						// Address:
+						var _tmp58 common.Address
+						if err := dec.ReadBytes(_tmp58[:]); err != nil {
+							return err
+						}
+						_tmp57.Address = _tmp58
+						// StorageKeys:
+						var _tmp59 []common.Hash
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						for dec.MoreDataInList() {
+							var _tmp60 common.Hash
+							if err := dec.ReadBytes(_tmp60[:]); err != nil {
+								return err
+							}
+							_tmp59 = append(_tmp59, _tmp60)
+						}
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+						_tmp57.StorageKeys = _tmp59
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+					}
+					_tmp56 = append(_tmp56, _tmp57)
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+				_tmp55.Reads = _tmp56
+				// Writes:
+				var _tmp61 []gadget.AccessTuple
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				for dec.MoreDataInList() {
+					var _tmp62 gadget.AccessTuple
+					{
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						// Address:
+						var _tmp63 common.Address
+						if err := dec.ReadBytes(_tmp63[:]); err != nil {
+							return err
+						}
+						_tmp62.Address = _tmp63
+						// StorageKeys:
+						var _tmp64 []common.Hash
+						if _, err := dec.List(); err != nil {
+							return err
+						}
+						for dec.MoreDataInList() {
+							var _tmp65 common.Hash
+							if err := dec.ReadBytes(_tmp65[:]); err != nil {
+								return err
+							}
+							_tmp64 = append(_tmp64, _tmp65)
+						}
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+						_tmp62.StorageKeys = _tmp64
+						if err := dec.ListEnd(); err != nil {
+							return err
+						}
+					}
+					_tmp61 = append(_tmp61, _tmp62)
+				}
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+				_tmp55.Writes = _tmp61
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp66 = &_tmp55
+		} else if _, err := dec.Raw(); err != nil {
+			return err
+		}
+		_tmp0.StrictAccessList = _tmp66
+		// ValidUntil:
+		var _tmp72 *gadget.Deadline
+		if _tmp73, _tmp74, err := dec.Kind(); err != nil {
+			return err
+		} else if _tmp74 != 0 || _tmp73 != rlp.List {
+			var _tmp69 gadget.Deadline
+			{
+				if _, err := dec.List(); err != nil {
+					return err
+				}
+				// Number:
+				_tmp70, err := dec.Uint64()
+				if err != nil {
+					return err
+				}
+				_tmp69.Number = _tmp70
+				// Time:
+				_tmp71, err := dec.Uint64()
+				if err != nil {
+					return err
+				}
+				_tmp69.Time = _tmp71
+				if err := dec.ListEnd(); err != nil {
+					return err
+				}
+			}
+			_tmp72 = &_tmp69
+		} else if _, err := dec.Raw(); err != nil {
+			return err
+		}
+		_tmp0.ValidUntil = _tmp72
+		if err := dec.ListEnd(); err != nil {
+			return err
+		}
+	}
+	*obj = _tmp0
+	return nil
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"bytes"
+
+	"execution/common"
+	"execution/common/merkle"
+	ethtypes "execution/core/types"
+)
+
+// Root returns the Merkle root of the transactions, see package merkle for the
+// tree layout. Leaves are the binary encodings of the transactions, so the root
+// commits to their hashes and signatures as well. The empty list has the zero
+// root.
+func (txs Transactions) Root() common.Hash {
+	items := make([][]byte, len(txs))
+	for i, tx := range txs {
+		items[i], _ = tx.MarshalBinary()
+	}
+	return merkle.Root(items)
+}
+
+// ReceiptsRoot returns the Merkle root of the receipts of a block. Leaves are
+// the consensus encodings of the receipts, the same the receipt tries of the
+// execution layer are built from. The empty list has the zero root.
+func ReceiptsRoot(receipts ethtypes.Receipts) common.Hash {
+	items := make([][]byte, len(receipts))
+	for i := range receipts {
+		var buf bytes.Buffer
+		receipts.EncodeIndex(i, &buf)
+		items[i] = buf.Bytes()
+	}
+	return merkle.Root(items)
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"runtime"
+	"sync"
+)
+
+// SenderCacher is a concurrent transaction sender recoverer and cacher.
+var SenderCacher = newTxSenderCacher(runtime.NumCPU())
+
+// txSenderCacherRequest is a request for recovering transaction senders with a
+// specific signature scheme and caching it into the transactions themselves.
+//
+// The inc field defines the number of transactions to skip after each recovery,
+// which is used to feed the same underlying input array to different threads but
+// ensure they process the early transactions fast.
+type txSenderCacherRequest struct {
+	signer Signer
+	txs    []*Transaction
+	inc    int
+	done   *sync.WaitGroup
+}
+
+// txSenderCacher is a helper structure to concurrently ecrecover transaction
+// senders from digital signatures on background threads.
+type txSenderCacher struct {
+	threads int
+	tasks   chan *txSenderCacherRequest
+}
+
+// newTxSenderCacher creates a new transaction sender background cacher and starts
+// as many processing goroutines as allowed by the GOMAXPROCS on construction.
+func newTxSenderCacher(threads int) *txSenderCacher {
+	cacher := &txSenderCacher{
+		tasks:   make(chan *txSenderCacherRequest, threads),
+		threads: threads,
+	}
+	for i := 0; i < threads; i++ {
+		go cacher.cache()
+	}
+	return cacher
+}
+
+// cache is an infinite loop, caching transaction senders from various forms of
+// data structures.
+func (cacher *txSenderCacher) cache() {
+	for task := range cacher.tasks {
+		for i := 0; i < len(task.txs); i += task.inc {
+			Sender(task.signer, task.txs[i])
+		}
+		task.done.Done()
+	}
+}
+
+// Recover recovers the senders from a batch of transactions and caches them so
+// that later Sender calls don't have to redo the ecrecover. The recovery runs on
+// background threads, the returned channel is closed once all of them finished.
+// Callers which only want to warm the cache may ignore it.
+func (cacher *txSenderCacher) Recover(signer Signer, txs []*Transaction) <-chan struct{} {
+	done := make(chan struct{})
+
+	// If there's nothing to recover, abort
+	if len(txs) == 0 {
+		close(done)
+		return done
+	}
+	// Ensure we have meaningful task sizes and schedule the recoveries
+	tasks := cacher.threads
+	if len(txs) < tasks*4 {
+		tasks = (len(txs) + 3) / 4
+	}
+	wg := new(sync.WaitGroup)
+	wg.Add(tasks)
+	for i := 0; i < tasks; i++ {
+		cacher.tasks <- &txSenderCacherRequest{
+			signer: signer,
+			txs:    txs[i:],
+			inc:    tasks,
+			done:   wg,
+		}
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// RecoverFromBlocks recovers the senders from a batch of blocks and caches them
+// so that later Sender calls don't have to redo the ecrecover.
+func (cacher *txSenderCacher) RecoverFromBlocks(signer Signer, blocks []*Block) <-chan struct{} {
+	count := 0
+	for _, block := range blocks {
+		count += len(block.Transactions())
+	}
+	txs := make([]*Transaction, 0, count)
+	for _, block := range blocks {
+		txs = append(txs, block.Transactions()...)
+	}
+	return cacher.Recover(signer, txs)
+}
@@ -4,9 +4,9 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"execution/common"
-	"execution/core/types/gadget"
 	"execution/crypto"
 	"execution/params"
+	"execution/types/gadget"
 	"math"
 	"math/big"
 )
@@ -50,52 +50,78 @@ type TxExtends struct {
 	Refund           *gadget.Refund     `json:"refund,omitempty"`
 	Extend           []byte             `json:"extend,omitempty"`
 	StrictAccessList *gadget.AccessList `json:"strictAccessList,omitempty"`
+	ValidUntil       *gadget.Deadline   `json:"validUntil,omitempty"`
 }
 
+// Type classifies the transaction by its fields. Withdraw transactions are signed
+// by the account paying for their output coins, recharge transactions are
+// authorised by the witnesses of their input coins and have no sender. Empty
+// coin lists count as absent, decoding doesn't keep them apart from nil ones.
 func (tx *Transaction) Type() TxType {
 	if (tx.From != common.Address{}) {
-		if tx.InputCoins == nil {
+		if len(tx.InputCoins) == 0 {
+			if len(tx.OutputCoins) != 0 {
+				return WithdrawTx
+			}
 			return NormalTx
 		} else {
 			return UnkownTx
 		}
 	} else {
-		if tx.InputCoins == nil && tx.OutputCoins != nil {
-			return WithdrawTx
-		}
-		if tx.InputCoins != nil && tx.OutputCoins == nil {
+		if len(tx.InputCoins) != 0 && len(tx.OutputCoins) == 0 {
 			return RechargeTx
 		}
 	}
 	return UnkownTx
 }
 
+// Serialize returns the JSON encoding of the transaction used by APIs. Hashes
+// and sizes are computed over the binary encoding instead, see MarshalBinary.
 func (tx *Transaction) Serialize() ([]byte, error) {
 	return json.Marshal(tx)
 }
 
+// Cost returns the gas fee cap times the gas limit plus what the transaction
+// transfers: the value of normal transactions and the output coins of
+// withdrawals. Missing amounts count as zero.
 func (tx *Transaction) Cost() *big.Int {
 	if tx.Type() == NormalTx {
-		gasCost := new(big.Int).Mul(tx.GasPrice.Price, new(big.Int).SetUint64(tx.GasLimit))
-		return gasCost.Add(gasCost, tx.Value)
+		gasCost := new(big.Int).Mul(tx.GasPrice.GasFeeCap(), new(big.Int).SetUint64(tx.GasLimit))
+		if tx.Value != nil {
+			gasCost.Add(gasCost, tx.Value)
+		}
+		return gasCost
 	}
 	if tx.Type() == WithdrawTx {
 		// withdraw Tx gets unique gas limit
-		gasCost := new(big.Int).Mul(tx.GasPrice.Price, new(big.Int).SetUint64(tx.GasLimit))
+		gasCost := new(big.Int).Mul(tx.GasPrice.GasFeeCap(), new(big.Int).SetUint64(tx.GasLimit))
 		for _, outputCoin := range tx.OutputCoins {
-			gasCost = gasCost.Add(gasCost, outputCoin.Amount)
+			if outputCoin.Amount != nil {
+				gasCost = gasCost.Add(gasCost, outputCoin.Amount)
+			}
 		}
 		return gasCost
 	}
 	if tx.Type() == RechargeTx {
 		// Recharge Tx gets unique gas limit
-		return new(big.Int).Mul(tx.GasPrice.Price, new(big.Int).SetUint64(tx.GasLimit))
+		return new(big.Int).Mul(tx.GasPrice.GasFeeCap(), new(big.Int).SetUint64(tx.GasLimit))
 	}
 	return nil
 }
 
+// CheckDeadline returns ErrTxExpired if the transaction may not be included in
+// a block with the given number and timestamp. A zero time skips the timestamp
+// check. The deadline is covered by the signature, so it can't be stripped.
+func (tx *Transaction) CheckDeadline(number, time uint64) error {
+	if tx.ValidUntil != nil && tx.ValidUntil.Passed(number, time) {
+		return ErrTxExpired
+	}
+	return nil
+}
+
+// Size returns the length of the binary encoding of the transaction.
 func (tx *Transaction) Size() uint64 {
-	ret, _ := tx.Serialize()
+	ret, _ := tx.MarshalBinary()
 	return uint64(len(ret))
 }
 
@@ -159,7 +185,7 @@ func toWordSize(size uint64) uint64 {
 	return (size + 31) / 32
 }
 
-func NewNormalTransaction(nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *gadget.GasPrice, data []byte, prv *ecdsa.PrivateKey) *Transaction {
+func NewNormalTransaction(nonce uint64, to common.Address, value *big.Int, gasLimit uint64, gasPrice *gadget.GasPrice, data []byte, signer Signer, prv *ecdsa.PrivateKey) *Transaction {
 	tx := &Transaction{
 		TxPreface: TxPreface{
 			From:     crypto.PubkeyToAddress(prv.PublicKey),
@@ -174,18 +200,11 @@ func NewNormalTransaction(nonce uint64, to common.Address, value *big.Int, gasLi
 		},
 	}
 
-	txBytes, _ := tx.Serialize()
-	hash := common.GenerateHash(txBytes)
-	var validate gadget.Validation
-	validate.Sign(hash, prv)
-
-	tx.TxHash = hash
-	tx.Validation = &validate
-
+	tx.Sign(signer, prv)
 	return tx
 }
 
-func NewWithdrawTransaction(nonce uint64, gasPrice *gadget.GasPrice, outputCoins []gadget.OutputCoin, prv *ecdsa.PrivateKey) *Transaction {
+func NewWithdrawTransaction(nonce uint64, gasPrice *gadget.GasPrice, outputCoins []gadget.OutputCoin, signer Signer, prv *ecdsa.PrivateKey) *Transaction {
 	tx := &Transaction{
 		TxPreface: TxPreface{
 			From:        crypto.PubkeyToAddress(prv.PublicKey),
@@ -195,14 +214,7 @@ func NewWithdrawTransaction(nonce uint64, gasPrice *gadget.GasPrice, outputCoins
 		},
 	}
 
-	txBytes, _ := tx.Serialize()
-	hash := common.GenerateHash(txBytes)
-	var validate gadget.Validation
-	validate.Sign(hash, prv)
-
-	tx.TxHash = hash
-	tx.Validation = &validate
-
+	tx.Sign(signer, prv)
 	return tx
 }
 
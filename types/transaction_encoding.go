@@ -0,0 +1,162 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	"execution/common"
+	"execution/rlp"
+	"execution/types/gadget"
+)
+
+// TxEncodingV1 is the version byte of the first binary transaction encoding,
+// an RLP list of the transaction fields in the order of rlpTx.
+const TxEncodingV1 byte = 0x01
+
+//go:generate go run ../rlp/rlpgen -type rlpTx -decoder -out gen_tx_rlp.go
+
+// rlpTx is the canonical field layout of a transaction in the binary encoding.
+// Fields may only be added at the end of a new encoding version.
+type rlpTx struct {
+	TxHash           common.Hash
+	From             common.Address
+	Nonce            uint64
+	GasLimit         uint64
+	GasPrice         []*big.Int // [price] for legacy pricing, [fee cap, tip cap] for dynamic fees
+	Value            *big.Int
+	Validation       *gadget.Validation `rlp:"nil"`
+	InputCoins       []gadget.InputCoin
+	Witnesses        []gadget.Witness
+	OutputCoins      []gadget.OutputCoin
+	To               common.Address
+	Data             []byte
+	AccessList       *gadget.AccessList `rlp:"nil"`
+	Refund           *gadget.Refund     `rlp:"nil"`
+	Extend           []byte
+	StrictAccessList *gadget.AccessList `rlp:"nil"`
+	ValidUntil       *gadget.Deadline   `rlp:"nil"`
+}
+
+// encodeGasPrice flattens a gas price into its canonical list form, which keeps
+// whether the price is legacy or dynamic apart from the zero values.
+func encodeGasPrice(gp *gadget.GasPrice) []*big.Int {
+	switch {
+	case gp == nil:
+		return nil
+	case gp.FeeCap == nil && gp.TipCap == nil:
+		if gp.Price == nil {
+			return nil
+		}
+		return []*big.Int{gp.Price}
+	default:
+		return []*big.Int{gp.GasFeeCap(), gp.GasTipCap()}
+	}
+}
+
+func decodeGasPrice(enc []*big.Int) (*gadget.GasPrice, error) {
+	switch len(enc) {
+	case 0:
+		return nil, nil
+	case 1:
+		return gadget.NewGasPrice(enc[0]), nil
+	case 2:
+		return gadget.NewDynamicGasPrice(enc[0], enc[1]), nil
+	}
+	return nil, fmt.Errorf("%w: gas price with %d fields", ErrInvalidTxEncoding, len(enc))
+}
+
+// MarshalBinary returns the canonical binary encoding of the transaction: the
+// encoding version byte followed by the RLP encoded fields. It is what hashes
+// and sizes of transactions are computed over, JSON is only used in APIs.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	enc := &rlpTx{
+		TxHash:           tx.TxHash,
+		From:             tx.From,
+		Nonce:            tx.Nonce,
+		GasLimit:         tx.GasLimit,
+		GasPrice:         encodeGasPrice(tx.GasPrice),
+		Value:            tx.Value,
+		Validation:       tx.Validation,
+		InputCoins:       tx.InputCoins,
+		Witnesses:        tx.Witnesses,
+		OutputCoins:      tx.OutputCoins,
+		To:               tx.To,
+		Data:             tx.Data,
+		AccessList:       tx.AccessList,
+		Refund:           tx.Refund,
+		Extend:           tx.Extend,
+		StrictAccessList: tx.StrictAccessList,
+		ValidUntil:       tx.ValidUntil,
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(TxEncodingV1)
+	if err := rlp.Encode(&buf, enc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical binary encoding of a transaction.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return fmt.Errorf("%w: empty input", ErrInvalidTxEncoding)
+	}
+	if b[0] != TxEncodingV1 {
+		return fmt.Errorf("%w: unknown version %d", ErrInvalidTxEncoding, b[0])
+	}
+	var dec rlpTx
+	if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+		return err
+	}
+	gasPrice, err := decodeGasPrice(dec.GasPrice)
+	if err != nil {
+		return err
+	}
+	*tx = Transaction{
+		TxPreface: TxPreface{
+			TxHash:      dec.TxHash,
+			From:        dec.From,
+			Nonce:       dec.Nonce,
+			GasLimit:    dec.GasLimit,
+			GasPrice:    gasPrice,
+			Value:       dec.Value,
+			Validation:  dec.Validation,
+			InputCoins:  dec.InputCoins,
+			Witnesses:   dec.Witnesses,
+			OutputCoins: dec.OutputCoins,
+		},
+		TxInner: TxInner{
+			To:         dec.To,
+			Data:       dec.Data,
+			AccessList: dec.AccessList,
+		},
+		TxExtends: TxExtends{
+			Refund:           dec.Refund,
+			Extend:           dec.Extend,
+			StrictAccessList: dec.StrictAccessList,
+			ValidUntil:       dec.ValidUntil,
+		},
+	}
+	return nil
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the transaction as an RLP string
+// holding its binary encoding.
+func (tx *Transaction) EncodeRLP(w io.Writer) error {
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
+	b, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return tx.UnmarshalBinary(b)
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/rlp"
+	"execution/types/gadget"
+)
+
+// Tests that transactions survive a round trip through the binary encoding and
+// its RLP wrapping with their hash and signature intact.
+func TestTransactionBinaryRoundTrip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewChainSigner(big.NewInt(1))
+
+	legacy := NewNormalTransaction(1, common.Address{0x01}, big.NewInt(100), 21000, gadget.NewGasPrice(big.NewInt(0)), nil, signer, key)
+	dynamic := NewNormalTransaction(2, common.Address{0x02}, big.NewInt(0), 50000, gadget.NewDynamicGasPrice(big.NewInt(7), big.NewInt(2)), []byte{0x01, 0x02}, signer, key)
+	dynamic.AccessList = &gadget.AccessList{Writes: []gadget.AccessTuple{{Address: common.Address{0x03}}}}
+	dynamic.ValidUntil = &gadget.Deadline{Number: 10}
+	dynamic.Sign(signer, key)
+
+	for i, tx := range []*Transaction{legacy, dynamic} {
+		enc, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatalf("tx %d: failed to encode: %v", i, err)
+		}
+		var dec Transaction
+		if err := rlp.DecodeBytes(enc, &dec); err != nil {
+			t.Fatalf("tx %d: failed to decode: %v", i, err)
+		}
+		if dec.TxHash != tx.TxHash || signer.Hash(&dec) != tx.TxHash {
+			t.Errorf("tx %d: hash mismatch: have %x, want %x", i, signer.Hash(&dec), tx.TxHash)
+		}
+		if from, err := Sender(signer, &dec); err != nil || from != tx.From {
+			t.Errorf("tx %d: sender mismatch: have %x (%v), want %x", i, from, err, tx.From)
+		}
+		if !reflect.DeepEqual(dec.GasPrice, tx.GasPrice) {
+			t.Errorf("tx %d: gas price mismatch: have %+v, want %+v", i, dec.GasPrice, tx.GasPrice)
+		}
+		if !reflect.DeepEqual(dec.ValidUntil, tx.ValidUntil) {
+			t.Errorf("tx %d: deadline mismatch: have %+v, want %+v", i, dec.ValidUntil, tx.ValidUntil)
+		}
+		if size := dec.Size(); size != tx.Size() {
+			t.Errorf("tx %d: size mismatch: have %d, want %d", i, size, tx.Size())
+		}
+	}
+	// Unknown encoding versions are refused
+	enc, _ := legacy.MarshalBinary()
+	enc[0] = TxEncodingV1 + 1
+	if err := new(Transaction).UnmarshalBinary(enc); !errors.Is(err, ErrInvalidTxEncoding) {
+		t.Errorf("unknown version error mismatch: have %v, want %v", err, ErrInvalidTxEncoding)
+	}
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"fmt"
+
+	"execution/common"
+	ethtypes "execution/core/types"
+	"execution/types/gadget"
+)
+
+// Envelope returns the transaction as a BHTx typed transaction of the execution
+// layer. The conversion is lossless, FromEnvelope restores the transaction with
+// its hash and signature.
+//
+// Transaction is the canonical transaction type: the pool, the block body and
+// the block validator all use it. The envelope only exists at the boundary to
+// the geth derived code of core/types, such as typed receipts, and no pool or
+// block code converts to it internally.
+func (tx *Transaction) Envelope() *ethtypes.Transaction {
+	inner := &ethtypes.BHTx{
+		TxHash:           tx.TxHash,
+		From:             tx.From,
+		Nonce:            tx.Nonce,
+		Gas:              tx.GasLimit,
+		GasPrice:         encodeGasPrice(tx.GasPrice),
+		Value:            tx.Value,
+		InputCoins:       tx.InputCoins,
+		Witnesses:        tx.Witnesses,
+		OutputCoins:      tx.OutputCoins,
+		To:               tx.To,
+		Data:             tx.Data,
+		AccessList:       tx.AccessList,
+		Refund:           tx.Refund,
+		Extend:           tx.Extend,
+		StrictAccessList: tx.StrictAccessList,
+		ValidUntil:       tx.ValidUntil,
+	}
+	if tx.Validation != nil {
+		inner.V, inner.R, inner.S = tx.Validation.V, tx.Validation.R, tx.Validation.S
+	}
+	return ethtypes.NewTx(inner)
+}
+
+// FromEnvelope converts a BHTx typed transaction of the execution layer back to
+// a transaction. Other transaction types are refused with
+// ethtypes.ErrTxTypeNotSupported, envelopes carrying a hash other than the one
+// of their content with ErrInvalidTxHash.
+//
+// Note, the sender is not verified, use Sender to do so.
+func FromEnvelope(etx *ethtypes.Transaction) (*Transaction, error) {
+	inner, ok := etx.BHTx()
+	if !ok {
+		return nil, fmt.Errorf("%w: envelope type %d", ethtypes.ErrTxTypeNotSupported, etx.Type())
+	}
+	gasPrice, err := decodeGasPrice(inner.GasPrice)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Transaction{
+		TxPreface: TxPreface{
+			TxHash:      inner.TxHash,
+			From:        inner.From,
+			Nonce:       inner.Nonce,
+			GasLimit:    inner.Gas,
+			GasPrice:    gasPrice,
+			Value:       inner.Value,
+			InputCoins:  inner.InputCoins,
+			Witnesses:   inner.Witnesses,
+			OutputCoins: inner.OutputCoins,
+		},
+		TxInner: TxInner{
+			To:         inner.To,
+			Data:       inner.Data,
+			AccessList: inner.AccessList,
+		},
+		TxExtends: TxExtends{
+			Refund:           inner.Refund,
+			Extend:           inner.Extend,
+			StrictAccessList: inner.StrictAccessList,
+			ValidUntil:       inner.ValidUntil,
+		},
+	}
+	// Unsigned transactions are enveloped with zero signature values
+	if inner.V.Sign() != 0 || inner.R.Sign() != 0 || inner.S.Sign() != 0 {
+		tx.Validation = &gadget.Validation{V: inner.V, R: inner.R, S: inner.S}
+	}
+	// The envelope is known by the carried hash, it must be the hash of the
+	// content by the signer of the chain the signature is for
+	var signer Signer = LegacySigner{}
+	if tx.Validation != nil {
+		if chainID := tx.Validation.ChainID(); chainID != nil {
+			signer = NewChainSigner(chainID)
+		}
+	}
+	if hash := signer.Hash(tx); hash != tx.TxHash || hash == (common.Hash{}) {
+		return nil, fmt.Errorf("%w: have %x, want %x", ErrInvalidTxHash, tx.TxHash, hash)
+	}
+	return tx, nil
+}
+
+// Envelopes converts a list of transactions with Envelope.
+func (txs Transactions) Envelopes() ethtypes.Transactions {
+	etxs := make(ethtypes.Transactions, len(txs))
+	for i, tx := range txs {
+		etxs[i] = tx.Envelope()
+	}
+	return etxs
+}
+
+// FromEnvelopes converts a list of execution layer transactions with
+// FromEnvelope, failing on the first one which isn't a BHTx.
+func FromEnvelopes(etxs ethtypes.Transactions) (Transactions, error) {
+	txs := make(Transactions, len(etxs))
+	for i, etx := range etxs {
+		tx, err := FromEnvelope(etx)
+		if err != nil {
+			return nil, fmt.Errorf("tx %d: %w", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	ethtypes "execution/core/types"
+	"execution/crypto"
+	"execution/types/gadget"
+)
+
+// Tests that every transaction type survives the round trip through the typed
+// envelope of the execution layer with its hash, signature and witnesses.
+func TestEnvelopeRoundTrip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := NewChainSigner(big.NewInt(1))
+
+	normal := NewNormalTransaction(1, common.Address{0x01}, big.NewInt(100), 50000, gadget.NewDynamicGasPrice(big.NewInt(7), big.NewInt(2)), []byte{0x01, 0x02}, signer, key)
+	normal.AccessList = &gadget.AccessList{Writes: []gadget.AccessTuple{{Address: common.Address{0x01}}}}
+	normal.ValidUntil = &gadget.Deadline{Number: 100}
+	normal.Sign(signer, key)
+
+	withdraw := NewWithdrawTransaction(2, gadget.NewGasPrice(big.NewInt(1)), []gadget.OutputCoin{{Amount: big.NewInt(5), Owner: common.Address{0x02}}}, signer, key)
+
+	recharge := NewRechargeTransaction(common.Hash{0x03}, []gadget.InputCoin{{TxHash: withdraw.TxHash, Amount: big.NewInt(5), Owner: from.Bytes()}}, []gadget.Witness{{}}, gadget.NewGasPrice(big.NewInt(1)), from)
+	recharge.Witnesses[0].Sign(recharge.WitnessHash(), key)
+	recharge.TxHash = recharge.SigHash()
+
+	for i, tx := range []*Transaction{normal, withdraw, recharge} {
+		// Pass the envelope through its canonical encoding along the way
+		blob, err := tx.Envelope().MarshalBinary()
+		if err != nil {
+			t.Fatalf("tx %d: failed to encode envelope: %v", i, err)
+		}
+		etx := new(ethtypes.Transaction)
+		if err := etx.UnmarshalBinary(blob); err != nil {
+			t.Fatalf("tx %d: failed to decode envelope: %v", i, err)
+		}
+		if etx.Type() != ethtypes.BHTxType || etx.Hash() != tx.TxHash {
+			t.Errorf("tx %d: envelope mismatch: have type %d hash %x, want type %d hash %x", i, etx.Type(), etx.Hash(), ethtypes.BHTxType, tx.TxHash)
+		}
+		dec, err := FromEnvelope(etx)
+		if err != nil {
+			t.Fatalf("tx %d: failed to convert envelope: %v", i, err)
+		}
+		have, _ := dec.MarshalBinary()
+		want, _ := tx.MarshalBinary()
+		if !bytes.Equal(have, want) {
+			t.Errorf("tx %d: encoding mismatch: have %x, want %x", i, have, want)
+		}
+		if dec.Type() != tx.Type() {
+			t.Errorf("tx %d: type mismatch: have %v, want %v", i, dec.Type(), tx.Type())
+		}
+		if tx.Validation != nil {
+			if sender, err := Sender(signer, dec); err != nil || sender != from {
+				t.Errorf("tx %d: sender mismatch: have %x (%v), want %x", i, sender, err, from)
+			}
+		}
+	}
+	// Envelopes whose hash doesn't match their content don't convert
+	forged := *normal
+	forged.Value = big.NewInt(1000)
+	if _, err := FromEnvelope(forged.Envelope()); !errors.Is(err, ErrInvalidTxHash) {
+		t.Errorf("forged conversion error mismatch: have %v, want %v", err, ErrInvalidTxHash)
+	}
+	// Other execution layer transactions don't convert
+	legacy := ethtypes.NewTx(&ethtypes.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, Value: big.NewInt(1)})
+	if _, err := FromEnvelope(legacy); !errors.Is(err, ethtypes.ErrTxTypeNotSupported) {
+		t.Errorf("legacy conversion error mismatch: have %v, want %v", err, ethtypes.ErrTxTypeNotSupported)
+	}
+}
@@ -0,0 +1,232 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"execution/common"
+	"execution/common/lru"
+	"execution/params"
+	"execution/rlp"
+	"execution/types/gadget"
+)
+
+// senderCacheSize is the number of recovered senders kept around to avoid
+// repeating the ecrecover of transactions seen more than once.
+const senderCacheSize = 4096
+
+// senderCacheKey identifies a signature over a transaction hash. The signature
+// is part of the key since it is not covered by the hash itself. V is kept in
+// full since it encodes the chain ID.
+type senderCacheKey struct {
+	hash    common.Hash
+	r, s, v common.Hash
+}
+
+var senderCache = lru.NewCache[senderCacheKey, common.Address](senderCacheSize)
+
+// Signer encapsulates the signature scheme of transactions: the hash a sender
+// signs, which doubles as the transaction hash, and the recovery of the sender.
+type Signer interface {
+	// Sender returns the sender address of the transaction.
+	Sender(tx *Transaction) (common.Address, error)
+
+	// ChainID returns the chain signatures are bound to, nil if they aren't.
+	ChainID() *big.Int
+
+	// Hash returns the hash to be signed by the sender.
+	Hash(tx *Transaction) common.Hash
+
+	// Equal returns true if the given signer is the same as the receiver.
+	Equal(Signer) bool
+}
+
+// MakeSigner returns the signer of the given chain, a ChainSigner if the chain
+// has an ID and a LegacySigner otherwise.
+func MakeSigner(config *params.ChainConfig) Signer {
+	if config == nil || config.ChainID == nil {
+		return LegacySigner{}
+	}
+	return NewChainSigner(config.ChainID)
+}
+
+// ChainSigner implements Signer with replay protection similar to EIP-155. The
+// signed hash commits to the chain ID and the V value of signatures encodes it
+// as chainID*2 + 35 + recovery id, so signatures for other chains are refused.
+type ChainSigner struct {
+	chainId *big.Int
+}
+
+func NewChainSigner(chainId *big.Int) ChainSigner {
+	if chainId == nil {
+		chainId = new(big.Int)
+	}
+	return ChainSigner{chainId: chainId}
+}
+
+func (s ChainSigner) ChainID() *big.Int {
+	return s.chainId
+}
+
+func (s ChainSigner) Equal(s2 Signer) bool {
+	chain, ok := s2.(ChainSigner)
+	return ok && chain.chainId.Cmp(s.chainId) == 0
+}
+
+func (s ChainSigner) Sender(tx *Transaction) (common.Address, error) {
+	return recoverSender(tx, s.hash, s.chainId)
+}
+
+// Hash returns the hash of the chain ID and the content of the transaction.
+// Transactions which can't be encoded hash to the zero hash.
+func (s ChainSigner) Hash(tx *Transaction) common.Hash {
+	hash, _ := s.hash(tx)
+	return hash
+}
+
+func (s ChainSigner) hash(tx *Transaction) (common.Hash, error) {
+	enc, err := tx.sigEncoding()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	enc, err = rlp.EncodeToBytes([]interface{}{s.chainId, enc})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.GenerateHash(enc), nil
+}
+
+// LegacySigner implements Signer without replay protection for chains without
+// an ID. Its signatures are valid on every such chain and refused by chain
+// signers.
+type LegacySigner struct{}
+
+func (s LegacySigner) ChainID() *big.Int {
+	return nil
+}
+
+func (s LegacySigner) Equal(s2 Signer) bool {
+	_, ok := s2.(LegacySigner)
+	return ok
+}
+
+func (s LegacySigner) Sender(tx *Transaction) (common.Address, error) {
+	return recoverSender(tx, (*Transaction).sigHash, nil)
+}
+
+func (s LegacySigner) Hash(tx *Transaction) common.Hash {
+	return tx.SigHash()
+}
+
+// SigHash returns the hash of the content of the transaction, which is what
+// legacy signatures and witnesses sign. It commits to the binary encoding of
+// every field of the transaction except TxHash and Validation, which are
+// derived from it. Transactions which can't be encoded hash to the zero hash.
+func (tx *Transaction) SigHash() common.Hash {
+	hash, _ := tx.sigHash()
+	return hash
+}
+
+func (tx *Transaction) sigHash() (common.Hash, error) {
+	enc, err := tx.sigEncoding()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.GenerateHash(enc), nil
+}
+
+// sigEncoding returns the binary encoding of the signed fields.
+func (tx *Transaction) sigEncoding() ([]byte, error) {
+	cpy := *tx
+	cpy.TxHash = common.Hash{}
+	cpy.Validation = nil
+
+	return cpy.MarshalBinary()
+}
+
+// WitnessHash returns the hash the witnesses of input coins sign. It commits to
+// the same fields as SigHash except the witnesses themselves.
+func (tx *Transaction) WitnessHash() common.Hash {
+	cpy := *tx
+	cpy.Witnesses = nil
+	return cpy.SigHash()
+}
+
+// VerifyWitness checks that the witness referenced by an input coin satisfies
+// the coin's spending condition and that the coin can be spent in a block with
+// the given number and timestamp. Unsatisfied conditions fail with the errors of
+// gadget.Witness.Satisfies.
+func VerifyWitness(tx *Transaction, inputIndex int, number, time uint64) error {
+	if inputIndex < 0 || inputIndex >= len(tx.InputCoins) {
+		return fmt.Errorf("%w: input %d of %d", ErrInvalidWitness, inputIndex, len(tx.InputCoins))
+	}
+	input := &tx.InputCoins[inputIndex]
+	if int(input.WitnessIndex) >= len(tx.Witnesses) {
+		return fmt.Errorf("%w: witness %d of %d", ErrInvalidWitness, input.WitnessIndex, len(tx.Witnesses))
+	}
+	cond, err := gadget.ParseCondition(input.Owner)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWitness, err)
+	}
+	if cond.Locked(number, time) {
+		return fmt.Errorf("%w: until block %d, time %d", ErrCoinLocked, cond.LockUntil.Number, cond.LockUntil.Time)
+	}
+	return tx.Witnesses[input.WitnessIndex].Satisfies(cond, tx.WitnessHash())
+}
+
+// Sign recomputes the hash of the transaction with the given signer and signs
+// it with the given key. It must be called again after changing any signed
+// field, e.g. the deadline.
+func (tx *Transaction) Sign(signer Signer, prv *ecdsa.PrivateKey) {
+	hash := signer.Hash(tx)
+	var validate gadget.Validation
+	validate.Sign(hash, signer.ChainID(), prv)
+
+	tx.TxHash = hash
+	tx.Validation = &validate
+}
+
+// Sender verifies that the transaction hash matches its content and returns
+// the address recovered from its signature by the given signer. Recovered
+// senders are cached, so repeated calls for the same signed transaction are
+// cheap.
+//
+// Note, the recovered address is not checked against the From field, callers
+// must do so if they rely on it.
+func Sender(signer Signer, tx *Transaction) (common.Address, error) {
+	return signer.Sender(tx)
+}
+
+// recoverSender checks that the transaction hash matches the hash of the signer
+// and recovers the sender of a signature for the given chain.
+func recoverSender(tx *Transaction, hash func(*Transaction) (common.Hash, error), chainID *big.Int) (common.Address, error) {
+	sig := tx.Validation
+	if sig == nil || sig.R == nil || sig.S == nil || sig.V == nil {
+		return common.Address{}, ErrInvalidSig
+	}
+	if sig.R.BitLen() > 256 || sig.S.BitLen() > 256 || sig.V.BitLen() > 256 {
+		return common.Address{}, ErrInvalidSig
+	}
+	// Check the chain first, the hash of another chain's transaction won't match
+	if have := sig.ChainID(); (have == nil) != (chainID == nil) || (have != nil && have.Cmp(chainID) != 0) {
+		return common.Address{}, fmt.Errorf("%w: have %v, want %v", ErrInvalidChainId, have, chainID)
+	}
+	if hash, err := hash(tx); err != nil || hash != tx.TxHash {
+		return common.Address{}, ErrInvalidTxHash
+	}
+	key := senderCacheKey{hash: tx.TxHash}
+	sig.R.FillBytes(key.r[:])
+	sig.S.FillBytes(key.s[:])
+	sig.V.FillBytes(key.v[:])
+
+	if from, ok := senderCache.Get(key); ok {
+		return from, nil
+	}
+	from, err := sig.GetFrom(tx.TxHash, chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	senderCache.Add(key, from)
+	return from, nil
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types/gadget"
+)
+
+// Tests that transactions signed for a chain are only accepted by the signer of
+// that chain, and that unprotected signatures are refused by chain signers.
+func TestSignerReplayProtection(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	signers := []Signer{LegacySigner{}, NewChainSigner(big.NewInt(1)), NewChainSigner(big.NewInt(1337))}
+	for i, signer := range signers {
+		tx := NewNormalTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, gadget.NewGasPrice(big.NewInt(1)), nil, signer, key)
+		for j, verifier := range signers {
+			sender, err := Sender(verifier, tx)
+			if i == j {
+				if err != nil || sender != from {
+					t.Errorf("signer %d: sender mismatch: have %x (%v), want %x", i, sender, err, from)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrInvalidChainId) {
+				t.Errorf("signer %d, verifier %d: error mismatch: have %v, want %v", i, j, err, ErrInvalidChainId)
+			}
+		}
+		if !signer.Equal(signers[i]) || signer.Equal(signers[(i+1)%len(signers)]) {
+			t.Errorf("signer %d: equality mismatch", i)
+		}
+	}
+	// Signatures can't be moved to another chain by rewriting V
+	signer := NewChainSigner(big.NewInt(1))
+	tx := NewNormalTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, gadget.NewGasPrice(big.NewInt(1)), nil, signer, key)
+	tx.Validation.V = new(big.Int).Add(tx.Validation.V, big.NewInt(2*1336))
+	if _, err := Sender(NewChainSigner(big.NewInt(1337)), tx); !errors.Is(err, ErrInvalidTxHash) {
+		t.Errorf("rewritten chain error mismatch: have %v, want %v", err, ErrInvalidTxHash)
+	}
+	if _, err := tx.Validation.GetFrom(tx.TxHash, big.NewInt(1)); !errors.Is(err, gadget.ErrInvalidChainID) {
+		t.Errorf("rewritten chain recovery error mismatch: have %v, want %v", err, gadget.ErrInvalidChainID)
+	}
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"execution/common"
+	"execution/common/merkle"
+	"execution/rlp"
+)
+
+// Withdrawal is the record of an output coin of an executed withdraw transaction.
+// The records of a block are committed to by the withdrawal root of its header,
+// so bridges watching the chain can verify withdrawals with a WithdrawalProof.
+type Withdrawal struct {
+	TxHash common.Hash    // Withdraw transaction creating the output
+	Index  uint32         // Index of the output in the transaction
+	Owner  common.Address // Owner of the output coin
+	Amount *big.Int       // Amount of the output coin
+}
+
+// Withdrawals is a list of withdrawal records in block order.
+type Withdrawals []*Withdrawal
+
+// Withdrawals returns the records of the outputs of a withdraw transaction, nil
+// for other transaction types.
+func (tx *Transaction) Withdrawals() Withdrawals {
+	if tx.Type() != WithdrawTx {
+		return nil
+	}
+	records := make(Withdrawals, len(tx.OutputCoins))
+	for i, output := range tx.OutputCoins {
+		records[i] = &Withdrawal{TxHash: tx.TxHash, Index: uint32(i), Owner: output.Owner, Amount: output.Amount}
+	}
+	return records
+}
+
+// TxWithdrawals returns the withdrawal records of a list of transactions in
+// order of the transactions and their outputs.
+func TxWithdrawals(txs Transactions) Withdrawals {
+	var records Withdrawals
+	for _, tx := range txs {
+		records = append(records, tx.Withdrawals()...)
+	}
+	return records
+}
+
+// encode returns the RLP encodings of the records, the leaves of the
+// withdrawal tree.
+func (ws Withdrawals) encode() [][]byte {
+	items := make([][]byte, len(ws))
+	for i, w := range ws {
+		items[i], _ = rlp.EncodeToBytes(w)
+	}
+	return items
+}
+
+// Root returns the Merkle root of the records, see package merkle for the tree
+// layout. Leaves are the RLP encodings of the records.
+func (ws Withdrawals) Root() common.Hash {
+	return merkle.Root(ws.encode())
+}
+
+// WithdrawalProof proves a withdrawal record to be included under the
+// withdrawal root of a block.
+type WithdrawalProof struct {
+	Index int           // Position of the record in the block's records
+	Count int           // Number of records of the block
+	Proof []common.Hash // Merkle proof of the record under the root
+}
+
+// Prove returns the record of an output of a withdraw transaction along with a
+// proof of its inclusion under the root of the records.
+func (ws Withdrawals) Prove(txHash common.Hash, index uint32) (*Withdrawal, *WithdrawalProof, error) {
+	for i, w := range ws {
+		if w.TxHash != txHash || w.Index != index {
+			continue
+		}
+		proof, err := merkle.Prove(ws.encode(), i)
+		if err != nil {
+			return nil, nil, err
+		}
+		return w, &WithdrawalProof{Index: i, Count: len(ws), Proof: proof}, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %x, %d", ErrWithdrawalNotFound, txHash, index)
+}
+
+// VerifyWithdrawal reports whether the proof proves the record to be included
+// under the withdrawal root.
+func VerifyWithdrawal(root common.Hash, w *Withdrawal, proof *WithdrawalProof) bool {
+	enc, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		return false
+	}
+	return merkle.Verify(root, enc, proof.Index, proof.Count, proof.Proof)
+}
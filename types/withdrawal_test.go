@@ -0,0 +1,49 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/types/gadget"
+)
+
+// Tests that every output of the withdraw transactions of a block can be proven
+// against the withdrawal root of its header, and that tampered records can't.
+func TestWithdrawalProofs(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	gasPrice := gadget.NewGasPrice(big.NewInt(1))
+	signer := NewChainSigner(big.NewInt(1))
+
+	txs := Transactions{
+		NewWithdrawTransaction(0, gasPrice, []gadget.OutputCoin{{Amount: big.NewInt(1), Owner: common.Address{0x01}}, {Amount: big.NewInt(2), Owner: common.Address{0x02}}}, signer, key),
+		NewNormalTransaction(1, common.Address{0x03}, big.NewInt(3), 21000, gasPrice, nil, signer, key),
+		NewWithdrawTransaction(2, gasPrice, []gadget.OutputCoin{{Amount: big.NewInt(4), Owner: common.Address{0x04}}}, signer, key),
+	}
+	body := NewBody(txs)
+	header := NewHeader(common.Hash{}, big.NewInt(1), 1000000).WithBody(body)
+	block := NewBlock(header, body)
+
+	records := block.Withdrawals()
+	if len(records) != 3 {
+		t.Fatalf("withdrawal count mismatch: have %d, want %d", len(records), 3)
+	}
+	for _, want := range records {
+		record, proof, err := block.WithdrawalProof(want.TxHash, want.Index)
+		if err != nil {
+			t.Fatalf("failed to prove withdrawal %x/%d: %v", want.TxHash, want.Index, err)
+		}
+		if !VerifyWithdrawal(block.Header().WithdrawalsRoot(), record, proof) {
+			t.Errorf("withdrawal %x/%d: proof failed to verify", want.TxHash, want.Index)
+		}
+		tampered := *record
+		tampered.Owner = common.Address{0xff}
+		if VerifyWithdrawal(block.Header().WithdrawalsRoot(), &tampered, proof) {
+			t.Errorf("withdrawal %x/%d: proof verified tampered owner", want.TxHash, want.Index)
+		}
+	}
+	if _, _, err := block.WithdrawalProof(txs[1].TxHash, 0); err == nil {
+		t.Errorf("proved output of a normal transaction")
+	}
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"execution/common"
+	"execution/crypto"
+	"execution/rlp"
+	"execution/types/gadget"
+)
+
+// Tests that input coins are only spendable by witnesses satisfying their
+// spending conditions, and that witnesses survive the transaction encoding.
+func TestVerifyWitness(t *testing.T) {
+	var keys [3]*ecdsa.PrivateKey
+	var addrs []common.Address
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs = append(addrs, crypto.PubkeyToAddress(keys[i].PublicKey))
+	}
+	preimage := []byte("secret")
+	owner := func(cond *gadget.Condition) []byte {
+		enc, err := cond.Owner()
+		if err != nil {
+			t.Fatalf("failed to encode condition: %v", err)
+		}
+		return enc
+	}
+	tests := []struct {
+		name    string
+		cond    *gadget.Condition
+		signers []int
+		reveal  []byte
+		number  uint64
+		err     error
+	}{
+		{name: "key", cond: gadget.NewKeyCondition(addrs[0]), signers: []int{0}},
+		{name: "wrong key", cond: gadget.NewKeyCondition(addrs[0]), signers: []int{1}, err: gadget.ErrUnexpectedSigner},
+		{name: "unsigned", cond: gadget.NewKeyCondition(addrs[0]), err: gadget.ErrNotEnoughSignatures},
+		{name: "multisig", cond: &gadget.Condition{Keys: addrs, Threshold: 2}, signers: []int{2, 0}},
+		{name: "multisig short", cond: &gadget.Condition{Keys: addrs, Threshold: 2}, signers: []int{1}, err: gadget.ErrNotEnoughSignatures},
+		{name: "multisig repeated", cond: &gadget.Condition{Keys: addrs, Threshold: 2}, signers: []int{1, 1}, err: gadget.ErrUnexpectedSigner},
+		{name: "hashlock", cond: &gadget.Condition{HashLock: crypto.Keccak256Hash(preimage)}, reveal: preimage},
+		{name: "hashlock wrong", cond: &gadget.Condition{HashLock: crypto.Keccak256Hash(preimage)}, reveal: []byte("guess"), err: gadget.ErrPreimageMismatch},
+		{name: "timelock", cond: &gadget.Condition{Keys: addrs[:1], Threshold: 1, LockUntil: gadget.Deadline{Number: 10}}, signers: []int{0}, number: 11},
+		{name: "timelock early", cond: &gadget.Condition{Keys: addrs[:1], Threshold: 1, LockUntil: gadget.Deadline{Number: 10}}, signers: []int{0}, number: 10, err: ErrCoinLocked},
+	}
+	for _, tt := range tests {
+		coins := []gadget.InputCoin{{TxHash: common.Hash{0x01}, Amount: big.NewInt(100), Owner: owner(tt.cond)}}
+		tx := NewRechargeTransaction(common.Hash{}, coins, []gadget.Witness{{Preimage: tt.reveal}}, gadget.NewGasPrice(big.NewInt(1)), addrs[0])
+		for _, signer := range tt.signers {
+			tx.Witnesses[0].Sign(tx.WitnessHash(), keys[signer])
+		}
+		// Decode the transaction to check the witness encoding along the way
+		enc, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", tt.name, err)
+		}
+		dec := new(Transaction)
+		if err := rlp.DecodeBytes(enc, dec); err != nil {
+			t.Fatalf("%s: failed to decode: %v", tt.name, err)
+		}
+		if err := VerifyWitness(dec, 0, tt.number, 0); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	// Witnesses don't cover themselves but everything else
+	tx := NewRechargeTransaction(common.Hash{}, []gadget.InputCoin{{Amount: big.NewInt(100), Owner: addrs[0].Bytes()}}, []gadget.Witness{{}}, gadget.NewGasPrice(big.NewInt(1)), addrs[0])
+	tx.Witnesses[0].Sign(tx.WitnessHash(), keys[0])
+	tx.To = addrs[1]
+	if err := VerifyWitness(tx, 0, 0, 0); !errors.Is(err, gadget.ErrUnexpectedSigner) {
+		t.Errorf("modified tx error mismatch: have %v, want %v", err, gadget.ErrUnexpectedSigner)
+	}
+	if err := VerifyWitness(tx, 1, 0, 0); !errors.Is(err, ErrInvalidWitness) {
+		t.Errorf("missing input error mismatch: have %v, want %v", err, ErrInvalidWitness)
+	}
+}
@@ -2,9 +2,16 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 )
 
+// ErrInputLimit is returned by decoders reading a value or stream extending past
+// their input limit.
+var ErrInputLimit = errors.New("input limit exceeded")
+
 type Decoder interface {
 	Decode(val interface{}) error // read
 }
@@ -13,9 +20,43 @@ type Encoder interface {
 	Encode(val interface{}) error // write
 }
 
+// Serializer creates encoders and decoders of a stream of values. Decoders read
+// at most inputLimit bytes of the stream in total and at most valueLimit bytes
+// for a single value, each unlimited if zero. Values are checked against the
+// limits before they're read wherever the format declares their size, and any
+// input past a limit fails decoding with ErrInputLimit.
 type Serializer interface {
-	GetEncoder(writer io.Writer) Encoder                    // write to writer
-	GetDecoder(reader io.Reader, inputLimit uint64) Decoder // read from reader
+	GetEncoder(writer io.Writer) Encoder                                // write to writer
+	GetDecoder(reader io.Reader, inputLimit, valueLimit uint64) Decoder // read from reader
+}
+
+// Names of the serializers available through NewSerializer. Any of them can be
+// compressed by appending SnappySuffix, e.g. "json+snappy".
+const (
+	JsonFormat   = "json"
+	RlpFormat    = "rlp"
+	BinaryFormat = "binary"
+	SnappySuffix = "+snappy"
+)
+
+// NewSerializer returns the serializer with the given name.
+func NewSerializer(name string) (Serializer, error) {
+	if strings.HasSuffix(name, SnappySuffix) {
+		s, err := NewSerializer(strings.TrimSuffix(name, SnappySuffix))
+		if err != nil {
+			return nil, err
+		}
+		return &SnappySerializer{Inner: s}, nil
+	}
+	switch name {
+	case JsonFormat:
+		return new(JsonSerializer), nil
+	case RlpFormat:
+		return new(RlpSerializer), nil
+	case BinaryFormat:
+		return new(BinarySerializer), nil
+	}
+	return nil, fmt.Errorf("unknown serializer %q", name)
 }
 
 type JsonSerializer struct{}
@@ -24,6 +65,85 @@ func (s *JsonSerializer) GetEncoder(writer io.Writer) Encoder {
 	return json.NewEncoder(writer)
 }
 
-func (s *JsonSerializer) GetDecoder(reader io.Reader, inputLimit uint64) Decoder {
-	return json.NewDecoder(reader)
+type jsonDecoder struct {
+	decoder *json.Decoder
+	value   *valueReader
+}
+
+func (d *jsonDecoder) Decode(val interface{}) error {
+	// JSON doesn't declare sizes, cap the input read for the value instead
+	d.value.start(uint64(d.decoder.InputOffset()))
+	return d.decoder.Decode(val)
+}
+
+func (s *JsonSerializer) GetDecoder(reader io.Reader, inputLimit, valueLimit uint64) Decoder {
+	value := &valueReader{reader: newLimitReader(reader, inputLimit), limit: valueLimit}
+	return &jsonDecoder{decoder: json.NewDecoder(value), value: value}
+}
+
+// valueReader caps the bytes read for a single value, counted from the offset
+// the previous value ended at. A zero limit is unlimited.
+type valueReader struct {
+	reader io.Reader
+	limit  uint64
+	read   uint64 // Bytes read from the reader so far
+	end    uint64 // Offset the current value must end by
+}
+
+// start marks the beginning of a value at the given offset of the input.
+func (v *valueReader) start(offset uint64) {
+	v.end = offset + v.limit
+}
+
+func (v *valueReader) Read(p []byte) (int, error) {
+	if v.limit == 0 {
+		return v.reader.Read(p)
+	}
+	if v.read >= v.end {
+		return 0, fmt.Errorf("%w: value over %d bytes", ErrInputLimit, v.limit)
+	}
+	if uint64(len(p)) > v.end-v.read {
+		p = p[:v.end-v.read]
+	}
+	n, err := v.reader.Read(p)
+	v.read += uint64(n)
+	return n, err
+}
+
+// limitReader reads up to a limit from a reader, failing with ErrInputLimit if
+// the reader has more. A zero limit is unlimited.
+type limitReader struct {
+	reader    io.Reader
+	limited   bool
+	remaining uint64
+	err       error // Sticky error once the limit is exceeded
+}
+
+func newLimitReader(reader io.Reader, limit uint64) *limitReader {
+	return &limitReader{reader: reader, limited: limit > 0, remaining: limit}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if !l.limited {
+		return l.reader.Read(p)
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.remaining == 0 {
+		// The limit is only exceeded if there's more to read
+		var probe [1]byte
+		n, err := io.ReadFull(l.reader, probe[:])
+		if n == 0 {
+			return 0, err
+		}
+		l.err = ErrInputLimit
+		return 0, l.err
+	}
+	if uint64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= uint64(n)
+	return n, err
 }
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	errNotBinaryMarshaler   = errors.New("value does not implement encoding.BinaryMarshaler")
+	errNotBinaryUnmarshaler = errors.New("value does not implement encoding.BinaryUnmarshaler")
+)
+
+// BinarySerializer encodes values with their own binary encoding, prefixing
+// each with its length as an uvarint. Values must implement
+// encoding.BinaryMarshaler to be encoded and encoding.BinaryUnmarshaler to be
+// decoded.
+type BinarySerializer struct{}
+
+type binaryEncoder struct {
+	writer io.Writer
+}
+
+func (e *binaryEncoder) Encode(val interface{}) error {
+	m, ok := val.(encoding.BinaryMarshaler)
+	if !ok {
+		return errNotBinaryMarshaler
+	}
+	payload, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	record := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(payload)), uint64(len(payload)))
+	_, err = e.writer.Write(append(record, payload...))
+	return err
+}
+
+type binaryDecoder struct {
+	input      *limitReader
+	reader     *bufio.Reader
+	valueLimit uint64
+}
+
+func (d *binaryDecoder) Decode(val interface{}) error {
+	u, ok := val.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return errNotBinaryUnmarshaler
+	}
+	size, err := binary.ReadUvarint(d.reader)
+	if err != nil {
+		return err
+	}
+	// Refuse records over the limits before allocating them
+	if d.valueLimit > 0 && size > d.valueLimit {
+		return fmt.Errorf("%w: binary record of %d bytes, max %d", ErrInputLimit, size, d.valueLimit)
+	}
+	if d.input.limited && size > d.input.remaining+uint64(d.reader.Buffered()) {
+		return fmt.Errorf("%w: binary record of %d bytes", ErrInputLimit, size)
+	}
+	// Grow the payload as it's read, sizes of unlimited input aren't trusted
+	var payload bytes.Buffer
+	if n, _ := io.CopyN(&payload, d.reader, int64(size)); uint64(n) != size {
+		return io.ErrUnexpectedEOF
+	}
+	return u.UnmarshalBinary(payload.Bytes())
+}
+
+func (s *BinarySerializer) GetEncoder(writer io.Writer) Encoder {
+	return &binaryEncoder{writer: writer}
+}
+
+func (s *BinarySerializer) GetDecoder(reader io.Reader, inputLimit, valueLimit uint64) Decoder {
+	input := newLimitReader(reader, inputLimit)
+	return &binaryDecoder{input: input, reader: bufio.NewReader(input), valueLimit: valueLimit}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"execution/rlp"
+)
+
+// RlpSerializer encodes values as a stream of RLP items.
+type RlpSerializer struct{}
+
+type rlpEncoder struct {
+	writer io.Writer
+}
+
+func (e *rlpEncoder) Encode(val interface{}) error {
+	return rlp.Encode(e.writer, val)
+}
+
+type rlpDecoder struct {
+	stream     *rlp.Stream
+	input      *limitReader
+	valueLimit uint64
+}
+
+func (d *rlpDecoder) Decode(val interface{}) error {
+	// Refuse values over the limit by their header, decoding reports other errors
+	if d.valueLimit > 0 {
+		if _, size, err := d.stream.Kind(); err == nil && size > d.valueLimit {
+			return fmt.Errorf("%w: rlp value of %d bytes", ErrInputLimit, size)
+		}
+	}
+	err := d.stream.Decode(val)
+	switch {
+	case errors.Is(err, rlp.ErrValueTooLarge):
+		return fmt.Errorf("%w: %v", ErrInputLimit, err)
+	case err == io.EOF:
+		// The stream ends at the input limit, check whether the input does too
+		if _, err := d.input.Read(make([]byte, 1)); err != nil {
+			return err
+		}
+		return ErrInputLimit
+	}
+	return err
+}
+
+func (s *RlpSerializer) GetEncoder(writer io.Writer) Encoder {
+	return &rlpEncoder{writer: writer}
+}
+
+func (s *RlpSerializer) GetDecoder(reader io.Reader, inputLimit, valueLimit uint64) Decoder {
+	input := newLimitReader(reader, inputLimit)
+	return &rlpDecoder{
+		stream:     rlp.NewStream(input, inputLimit),
+		input:      input,
+		valueLimit: valueLimit,
+	}
+}
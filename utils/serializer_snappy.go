@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// SnappySerializer compresses the output of another serializer with the snappy
+// framing format.
+type SnappySerializer struct {
+	Inner Serializer
+}
+
+type snappyEncoder struct {
+	inner      Encoder
+	compressor *snappy.Writer
+}
+
+// Encode encodes and compresses a value, flushing it to the writer at once.
+func (e *snappyEncoder) Encode(val interface{}) error {
+	if err := e.inner.Encode(val); err != nil {
+		return err
+	}
+	return e.compressor.Flush()
+}
+
+func (s *SnappySerializer) GetEncoder(writer io.Writer) Encoder {
+	compressor := snappy.NewBufferedWriter(writer)
+	return &snappyEncoder{
+		inner:      s.Inner.GetEncoder(compressor),
+		compressor: compressor,
+	}
+}
+
+// GetDecoder returns a decoder of the inner serializer reading the decompressed
+// stream. The limits apply to the decompressed data.
+func (s *SnappySerializer) GetDecoder(reader io.Reader, inputLimit, valueLimit uint64) Decoder {
+	return s.Inner.GetDecoder(snappy.NewReader(reader), inputLimit, valueLimit)
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"execution/rlp"
+)
+
+var testFormats = []string{JsonFormat, RlpFormat, BinaryFormat, JsonFormat + SnappySuffix, RlpFormat + SnappySuffix, BinaryFormat + SnappySuffix}
+
+type testRecord struct {
+	Name  string
+	Value uint64
+}
+
+func (r *testRecord) MarshalBinary() ([]byte, error) {
+	return rlp.EncodeToBytes(r)
+}
+
+func (r *testRecord) UnmarshalBinary(b []byte) error {
+	return rlp.DecodeBytes(b, r)
+}
+
+// Tests that a stream of values survives a round trip through every serializer
+// selectable by name, compressed or not.
+func TestSerializerRoundTrip(t *testing.T) {
+	records := []*testRecord{{"a", 1}, {"", 0}, {"ccc", 1 << 40}}
+
+	for _, name := range testFormats {
+		serializer, err := NewSerializer(name)
+		if err != nil {
+			t.Fatalf("%s: failed to create serializer: %v", name, err)
+		}
+		var buf bytes.Buffer
+		encoder := serializer.GetEncoder(&buf)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				t.Fatalf("%s: failed to encode: %v", name, err)
+			}
+		}
+		decoder := serializer.GetDecoder(bytes.NewReader(buf.Bytes()), 0, 0)
+		for i, want := range records {
+			have := new(testRecord)
+			if err := decoder.Decode(have); err != nil {
+				t.Fatalf("%s: failed to decode record %d: %v", name, i, err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("%s: record %d mismatch: have %+v, want %+v", name, i, have, want)
+			}
+		}
+		if err := decoder.Decode(new(testRecord)); err != io.EOF {
+			t.Errorf("%s: end of stream error mismatch: have %v, want %v", name, err, io.EOF)
+		}
+	}
+	if _, err := NewSerializer("xml"); err == nil {
+		t.Errorf("unknown serializer created")
+	}
+}
+
+// encodeTestRecords encodes records with a serializer, returning the stream and
+// the offsets each record ends at.
+func encodeTestRecords(t testing.TB, serializer Serializer, records ...*testRecord) ([]byte, []int) {
+	var (
+		buf     bytes.Buffer
+		ends    []int
+		encoder = serializer.GetEncoder(&buf)
+	)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		ends = append(ends, buf.Len())
+	}
+	return buf.Bytes(), ends
+}
+
+// Tests that decoders of uncompressed streams refuse values and streams extending
+// past their input limit with ErrInputLimit.
+func TestSerializerInputLimit(t *testing.T) {
+	for _, name := range []string{JsonFormat, RlpFormat, BinaryFormat} {
+		serializer, _ := NewSerializer(name)
+		stream, ends := encodeTestRecords(t, serializer, &testRecord{"a", 1}, &testRecord{"bbbbbbbbbbbbbbbbbbbbbbbb", 2})
+
+		// Values fitting into the limit decode, the stream past it fails
+		decoder := serializer.GetDecoder(bytes.NewReader(stream), uint64(ends[0]), 0)
+		if err := decoder.Decode(new(testRecord)); err != nil {
+			t.Fatalf("%s: failed to decode record within limit: %v", name, err)
+		}
+		if err := decoder.Decode(new(testRecord)); !errors.Is(err, ErrInputLimit) {
+			t.Errorf("%s: error past limit mismatch: have %v, want %v", name, err, ErrInputLimit)
+		}
+		// Values crossing the limit fail (JSON values end with a newline, cut into the value itself)
+		decoder = serializer.GetDecoder(bytes.NewReader(stream), uint64(ends[1]-2), 0)
+		if err := decoder.Decode(new(testRecord)); err != nil {
+			t.Fatalf("%s: failed to decode record within limit: %v", name, err)
+		}
+		if err := decoder.Decode(new(testRecord)); !errors.Is(err, ErrInputLimit) {
+			t.Errorf("%s: error across limit mismatch: have %v, want %v", name, err, ErrInputLimit)
+		}
+		// Streams ending at the limit end normally
+		decoder = serializer.GetDecoder(bytes.NewReader(stream), uint64(len(stream)), 0)
+		for i := range ends {
+			if err := decoder.Decode(new(testRecord)); err != nil {
+				t.Fatalf("%s: failed to decode record %d: %v", name, i, err)
+			}
+		}
+		if err := decoder.Decode(new(testRecord)); err != io.EOF {
+			t.Errorf("%s: end of stream error mismatch: have %v, want %v", name, err, io.EOF)
+		}
+	}
+}
+
+// Tests that decoders refuse single values larger than their value limit with
+// ErrInputLimit, however much input is left.
+func TestSerializerValueLimit(t *testing.T) {
+	for _, name := range testFormats {
+		serializer, _ := NewSerializer(name)
+		stream, ends := encodeTestRecords(t, serializer, &testRecord{"a", 1}, &testRecord{"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", 2})
+
+		// The first record fits the limit, the second doesn't
+		decoder := serializer.GetDecoder(bytes.NewReader(stream), 0, 32)
+		if err := decoder.Decode(new(testRecord)); err != nil {
+			t.Fatalf("%s: failed to decode record within limit: %v", name, err)
+		}
+		if err := decoder.Decode(new(testRecord)); !errors.Is(err, ErrInputLimit) {
+			t.Errorf("%s: error over value limit mismatch: have %v, want %v", name, err, ErrInputLimit)
+		}
+		// Values fitting the limit decode until the end of the stream
+		decoder = serializer.GetDecoder(bytes.NewReader(stream), 0, 64)
+		for i := range ends {
+			if err := decoder.Decode(new(testRecord)); err != nil {
+				t.Fatalf("%s: failed to decode record %d: %v", name, i, err)
+			}
+		}
+		if err := decoder.Decode(new(testRecord)); err != io.EOF {
+			t.Errorf("%s: end of stream error mismatch: have %v, want %v", name, err, io.EOF)
+		}
+	}
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += n
+	return n, err
+}
+
+// FuzzSerializerDecode checks that decoders don't crash on arbitrary input and
+// never read further than one byte past their input limit to detect input
+// exceeding it.
+func FuzzSerializerDecode(f *testing.F) {
+	for _, name := range testFormats {
+		serializer, _ := NewSerializer(name)
+		stream, ends := encodeTestRecords(f, serializer, &testRecord{"a", 1}, &testRecord{"", 0}, &testRecord{"ccc", 1 << 40})
+		f.Add(stream, uint16(0))
+		f.Add(stream, uint16(ends[0]))
+		f.Add(stream, uint16(len(stream)-1))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, limit uint16) {
+		for _, name := range testFormats {
+			serializer, _ := NewSerializer(name)
+			input := &countingReader{reader: bytes.NewReader(data)}
+			decoder := serializer.GetDecoder(input, uint64(limit), uint64(limit))
+			for i := 0; i < 16; i++ {
+				if err := decoder.Decode(new(testRecord)); err != nil {
+					break
+				}
+			}
+			if !strings.HasSuffix(name, SnappySuffix) && limit > 0 && input.count > int(limit)+1 {
+				t.Fatalf("%s: read past input limit: have %d bytes, limit %d", name, input.count, limit)
+			}
+		}
+	})
+}